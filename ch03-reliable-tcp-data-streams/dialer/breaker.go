@@ -0,0 +1,136 @@
+package dialer
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Defaults for the circuit breaker
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned when an address failed too many times in a row
+// and the breaker doesn't let new connection attempts through
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Breaker is a per-address circuit breaker: after Threshold consecutive
+// failures it opens and fails fast for Cooldown, then lets a single trial
+// attempt through (half-open) to decide whether to close again.
+//
+// Dialer reports only refused connections as failures: a refusal means
+// the host is up and nothing listens, while timeouts and unreachable
+// networks are often local or transient and are left to the retries.
+//
+// The zero value is ready to use.
+type Breaker struct {
+	// Consecutive failures to open the circuit (5 if not set)
+	Threshold int
+	// Time the circuit stays open before a trial attempt (30 s if not set)
+	Cooldown time.Duration
+
+	mu     sync.Mutex
+	states map[string]*breakerState
+}
+
+// State of the circuit for a single address
+type breakerState struct {
+	// Consecutive failures
+	failures int
+	// When the circuit was opened (zero if closed)
+	openedAt time.Time
+	// Whether a trial attempt is in progress (half-open)
+	trial bool
+}
+
+// Func Allow - check whether an attempt to the address may proceed
+func (b *Breaker) Allow(address string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(address)
+
+	// Closed circuit: go ahead
+	if state.openedAt.IsZero() {
+		return nil
+	}
+
+	// Open circuit: fail fast until the cooldown passes,
+	// then let exactly one trial attempt through
+	if state.trial || time.Since(state.openedAt) < b.cooldown() {
+		return ErrCircuitOpen
+	}
+
+	state.trial = true
+	return nil
+}
+
+// Func Success - report a successful attempt, closing the circuit
+func (b *Breaker) Success(address string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.states, address)
+}
+
+// Func Failure - report a failed attempt, opening the circuit
+// if the threshold is reached or the trial attempt failed
+func (b *Breaker) Failure(address string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(address)
+	state.failures++
+
+	if state.trial || state.failures >= b.threshold() {
+		state.openedAt = time.Now()
+		state.trial = false
+	}
+}
+
+// Func Cancel - report an attempt which proved nothing about the address
+// (e.g., canceled by the caller): a trial attempt is released so that the
+// next one may go through, but the circuit stays open
+func (b *Breaker) Cancel(address string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if state, ok := b.states[address]; ok {
+		state.trial = false
+	}
+}
+
+// Func state - get (or create) the state of the address, must be called under lock
+func (b *Breaker) state(address string) *breakerState {
+	if b.states == nil {
+		b.states = make(map[string]*breakerState)
+	}
+
+	state, ok := b.states[address]
+	if !ok {
+		state = new(breakerState)
+		b.states[address] = state
+	}
+
+	return state
+}
+
+// Func threshold - configured threshold or the default one
+func (b *Breaker) threshold() int {
+	if b.Threshold <= 0 {
+		return defaultBreakerThreshold
+	}
+
+	return b.Threshold
+}
+
+// Func cooldown - configured cooldown or the default one
+func (b *Breaker) cooldown() time.Duration {
+	if b.Cooldown <= 0 {
+		return defaultBreakerCooldown
+	}
+
+	return b.Cooldown
+}
@@ -0,0 +1,151 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+)
+
+// Defaults for the retrying dialer
+const (
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 100 * time.Millisecond
+	defaultMaxDelay    = 5 * time.Second
+)
+
+// Dialer retries failed connection attempts with exponential backoff
+// and full jitter, optionally guarded by a per-address circuit breaker.
+//
+// The overall deadline comes from the context passed to DialContext
+// (and Timeout if set), the per-attempt one from AttemptTimeout.
+type Dialer struct {
+	// Function to make a single attempt (net.Dialer.DialContext if not set),
+	// e.g., HappyEyeballs.DialContext
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+	// Maximum number of attempts (3 if not set)
+	MaxAttempts int
+	// Timeout of a single attempt (no timeout if not set)
+	AttemptTimeout time.Duration
+	// Timeout of all attempts together (no timeout if not set)
+	Timeout time.Duration
+	// Backoff before the second attempt, doubled every time (100 ms if not set)
+	BaseDelay time.Duration
+	// Upper limit of the backoff (5 s if not set)
+	MaxDelay time.Duration
+	// Circuit breaker shared between dials (no breaker if not set)
+	Breaker *Breaker
+}
+
+// Func DialContext - dial the address, retrying on failures
+func (d *Dialer) DialContext(
+	ctx context.Context,
+	network, address string,
+) (net.Conn, error) {
+	// Apply the overall timeout
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+
+	maxAttempts := d.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	var errs []error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		// Wait before retrying (or give up if the context is done)
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, errors.Join(append(errs, ctx.Err())...)
+			case <-time.After(d.backoff(attempt)):
+			}
+		}
+
+		// Fail fast if the address keeps failing
+		if d.Breaker != nil {
+			if err := d.Breaker.Allow(address); err != nil {
+				return nil, errors.Join(append(errs, fmt.Errorf("%s: %w", address, err))...)
+			}
+		}
+
+		conn, err := d.attempt(ctx, network, address)
+		if err == nil {
+			if d.Breaker != nil {
+				d.Breaker.Success(address)
+			}
+			return conn, nil
+		}
+
+		// The caller gave up: neither retry nor blame the address
+		if ctx.Err() != nil {
+			if d.Breaker != nil {
+				d.Breaker.Cancel(address)
+			}
+			return nil, errors.Join(append(errs, err)...)
+		}
+
+		// Only refusals count against the address (see Breaker)
+		if d.Breaker != nil {
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				d.Breaker.Failure(address)
+			} else {
+				d.Breaker.Cancel(address)
+			}
+		}
+
+		errs = append(errs, fmt.Errorf("attempt %d: %w", attempt+1, err))
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// Func attempt - make a single attempt within its own timeout
+func (d *Dialer) attempt(
+	ctx context.Context,
+	network, address string,
+) (net.Conn, error) {
+	if d.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.AttemptTimeout)
+		defer cancel()
+	}
+
+	dial := d.Dial
+	if dial == nil {
+		var nd net.Dialer
+		dial = nd.DialContext
+	}
+
+	return dial(ctx, network, address)
+}
+
+// Func backoff - random delay before the given attempt:
+// uniformly distributed in [0, min(MaxDelay, BaseDelay * 2^(attempt-1))]
+func (d *Dialer) backoff(attempt int) time.Duration {
+	base := d.BaseDelay
+	if base <= 0 {
+		base = defaultBaseDelay
+	}
+
+	maxDelay := d.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxDelay
+	}
+
+	// Double the delay, stopping at the limit (also prevents overflows)
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxDelay)
+
+	// Full jitter spreads retries of many clients over time
+	return rand.N(delay + 1)
+}
@@ -0,0 +1,252 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// Helper to get an address nobody listens on
+func freeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	return addr
+}
+
+func TestDialerRetry(t *testing.T) {
+	addr := freeAddr(t)
+
+	// Start listening only after a while, so that the first attempts fail
+	listening := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Error(err)
+			close(listening)
+			return
+		}
+		listening <- listener
+	}()
+
+	d := Dialer{
+		MaxAttempts: 20,
+		BaseDelay:   20 * time.Millisecond,
+		MaxDelay:    100 * time.Millisecond,
+	}
+
+	conn, err := d.DialContext(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+
+	if listener, ok := <-listening; ok {
+		_ = listener.Close()
+	}
+}
+
+func TestDialerMaxAttempts(t *testing.T) {
+	// Count attempts through a custom dial function
+	attempts := 0
+	d := Dialer{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			attempts++
+			var nd net.Dialer
+			return nd.DialContext(ctx, network, address)
+		},
+	}
+
+	_, err := d.DialContext(context.Background(), "tcp", freeAddr(t))
+	if err == nil {
+		t.Fatal("expected dial to fail")
+	}
+
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts; actual %d", attempts)
+	}
+}
+
+func TestDialerOverallTimeout(t *testing.T) {
+	// Lots of attempts with long delays, but a short overall timeout
+	d := Dialer{
+		MaxAttempts: 100,
+		BaseDelay:   time.Second,
+		Timeout:     300 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err := d.DialContext(context.Background(), "tcp", freeAddr(t))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded; actual %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("dial took too long: %s", elapsed)
+	}
+}
+
+func TestDialerCircuitBreaker(t *testing.T) {
+	addr := freeAddr(t)
+
+	// Open after two failures, try again after 200 ms
+	breaker := &Breaker{Threshold: 2, Cooldown: 200 * time.Millisecond}
+	d := Dialer{MaxAttempts: 1, Breaker: breaker}
+
+	// Two refused connections open the circuit
+	for i := 0; i < 2; i++ {
+		_, err := d.DialContext(context.Background(), "tcp", addr)
+		if err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("%d: expected connection error; actual %v", i, err)
+		}
+	}
+
+	// Now it fails fast
+	_, err := d.DialContext(context.Background(), "tcp", addr)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit; actual %v", err)
+	}
+
+	// After the cooldown, a trial attempt goes through and succeeds
+	time.Sleep(250 * time.Millisecond)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	conn, err := d.DialContext(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+
+	// The circuit is closed again
+	if err := breaker.Allow(addr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	breaker := &Breaker{Threshold: 1, Cooldown: 50 * time.Millisecond}
+	addr := "127.0.0.1:1"
+
+	breaker.Failure(addr)
+	if err := breaker.Allow(addr); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit; actual %v", err)
+	}
+
+	// Only one trial is allowed after the cooldown
+	time.Sleep(60 * time.Millisecond)
+	if err := breaker.Allow(addr); err != nil {
+		t.Fatalf("expected trial to be allowed; actual %v", err)
+	}
+	if err := breaker.Allow(addr); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected second trial to be rejected; actual %v", err)
+	}
+
+	// A failed trial opens the circuit again
+	breaker.Failure(addr)
+	if err := breaker.Allow(addr); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit; actual %v", err)
+	}
+}
+
+func TestDialerCanceledTrial(t *testing.T) {
+	breaker := &Breaker{Threshold: 1, Cooldown: 50 * time.Millisecond}
+	addr := "127.0.0.1:1"
+
+	// The trial attempt hangs until the caller gives up
+	d := Dialer{
+		MaxAttempts: 1,
+		Breaker:     breaker,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	breaker.Failure(addr)
+	time.Sleep(60 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := d.DialContext(ctx, "tcp", addr); errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected trial to be allowed; actual %v", err)
+	}
+
+	// The canceled trial proved nothing: another one may go through,
+	// but the circuit is still open
+	if err := breaker.Allow(addr); err != nil {
+		t.Fatalf("expected another trial to be allowed; actual %v", err)
+	}
+	if err := breaker.Allow(addr); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit; actual %v", err)
+	}
+}
+
+func TestDialerCountsRefusals(t *testing.T) {
+	breaker := &Breaker{Threshold: 1, Cooldown: time.Minute}
+	timeout := errors.New("i/o timeout")
+	d := Dialer{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		Breaker:     breaker,
+		Dial: func(context.Context, string, string) (net.Conn, error) {
+			return nil, timeout
+		},
+	}
+
+	// Other errors are retried but don't open the circuit
+	if _, err := d.DialContext(context.Background(), "tcp", "127.0.0.1:1"); !errors.Is(err, timeout) {
+		t.Fatalf("expected %v; actual %v", timeout, err)
+	}
+	if err := breaker.Allow("127.0.0.1:1"); err != nil {
+		t.Fatalf("expected closed circuit; actual %v", err)
+	}
+
+	// A refusal opens it
+	d.Dial = nil
+	addr := freeAddr(t)
+	if _, err := d.DialContext(context.Background(), "tcp", addr); err == nil {
+		t.Fatal("expected connection error")
+	}
+	if err := breaker.Allow(addr); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit; actual %v", err)
+	}
+}
+
+func TestDialerBackoff(t *testing.T) {
+	d := Dialer{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	// Upper bounds: 100ms, 200ms, 400ms, 800ms, then capped at 1s
+	for attempt, limit := range []time.Duration{
+		0,
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	} {
+		if attempt == 0 {
+			continue
+		}
+
+		for i := 0; i < 100; i++ {
+			if delay := d.backoff(attempt); delay < 0 || delay > limit {
+				t.Fatalf("attempt %d: delay %s out of [0, %s]", attempt, delay, limit)
+			}
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Recommended delay between connection attempts (RFC 8305, section 5)
+const defaultAttemptDelay = 250 * time.Millisecond
+
+// Attempt describes a single connection attempt of a race
+type Attempt struct {
+	// Address dialed
+	Address string
+	// Time since the beginning of the race when the attempt started
+	Start time.Duration
+	// Time the attempt took (until success, failure or cancellation)
+	Duration time.Duration
+	// Error if the attempt failed or was canceled
+	Err error
+}
+
+// Result of a race: the winning connection and all attempts made
+type Result struct {
+	Conn     net.Conn
+	Attempts []Attempt
+	// Index of the winning attempt
+	Winner int
+}
+
+// HappyEyeballs resolves all addresses of a host and races connections
+// to them with staggered starts (RFC 8305), returning the first one established.
+//
+// Its DialContext method can be plugged into http.Transport as is.
+type HappyEyeballs struct {
+	// Delay before starting the next attempt if the current one
+	// neither succeeded nor failed yet (250 ms if not set)
+	AttemptDelay time.Duration
+	// Resolver to look up the host (net.DefaultResolver if not set)
+	Resolver *net.Resolver
+	// Dialer for every single attempt (zero net.Dialer if not set)
+	Dialer *net.Dialer
+}
+
+// Func DialContext - race connections to the address and return the winner
+func (h *HappyEyeballs) DialContext(
+	ctx context.Context,
+	network, address string,
+) (net.Conn, error) {
+	result, err := h.Race(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Conn, nil
+}
+
+// Func Race - resolve the address and race connections to all its IPs,
+// returning the winner along with the timing of every attempt
+func (h *HappyEyeballs) Race(
+	ctx context.Context,
+	network, address string,
+) (*Result, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := h.lookup(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the list of addresses to race, alternating address families
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range interleave(ips) {
+		addrs = append(addrs, net.JoinHostPort(ip.String(), port))
+	}
+
+	return h.race(ctx, network, addrs)
+}
+
+// Func lookup - resolve the host into IPs allowed by the network
+func (h *HappyEyeballs) lookup(
+	ctx context.Context,
+	network, host string,
+) ([]net.IP, error) {
+	// Nothing to resolve for IP literals
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	resolver := h.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	// Query both A and AAAA records (unless restricted by the network)
+	lookupNetwork := "ip"
+	switch network {
+	case "tcp4":
+		lookupNetwork = "ip4"
+	case "tcp6":
+		lookupNetwork = "ip6"
+	}
+
+	ips, err := resolver.LookupIP(ctx, lookupNetwork, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses found for %q", host)
+	}
+
+	return ips, nil
+}
+
+// Func interleave - order IPs alternating families, IPv6 first (RFC 8305, section 4)
+func interleave(ips []net.IP) []net.IP {
+	var v6, v4 []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+
+	ordered := make([]net.IP, 0, len(ips))
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			ordered = append(ordered, v6[i])
+		}
+		if i < len(v4) {
+			ordered = append(ordered, v4[i])
+		}
+	}
+
+	return ordered
+}
+
+// Outcome of a single attempt sent back to the race
+type outcome struct {
+	index int
+	conn  net.Conn
+	err   error
+}
+
+// Func race - dial the addresses in order with staggered starts,
+// cancel the losers as soon as one succeeds
+func (h *HappyEyeballs) race(
+	ctx context.Context,
+	network string,
+	addrs []string,
+) (*Result, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("no addresses to dial")
+	}
+
+	// Set defaults
+	delay := h.AttemptDelay
+	if delay <= 0 {
+		delay = defaultAttemptDelay
+	}
+
+	dialer := h.Dialer
+	if dialer == nil {
+		dialer = new(net.Dialer)
+	}
+
+	// Cancel all attempts still in progress when the race is over
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered so that the losers never block
+	outcomes := make(chan outcome, len(addrs))
+	attempts := make([]Attempt, len(addrs))
+	finished := make([]bool, len(addrs))
+	begin := time.Now()
+
+	// Helper to start the next attempt
+	next := 0
+	pending := 0
+	startNext := func() {
+		i := next
+		next++
+		pending++
+
+		attempts[i] = Attempt{Address: addrs[i], Start: time.Since(begin)}
+		go func() {
+			conn, err := dialer.DialContext(ctx, network, addrs[i])
+			outcomes <- outcome{index: i, conn: conn, err: err}
+		}()
+	}
+
+	// Helper to arm the timer for the next attempt (if any)
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	armTimer := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		timeout = nil
+
+		if next < len(addrs) {
+			timer = time.NewTimer(delay)
+			timeout = timer.C
+		}
+	}
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	startNext()
+	armTimer()
+
+	var errs []error
+	for pending > 0 {
+		select {
+		case <-timeout:
+			// The current attempt is taking too long, start the next one in parallel
+			startNext()
+			armTimer()
+		case o := <-outcomes:
+			pending--
+			finished[o.index] = true
+			attempts[o.index].Duration = time.Since(begin) - attempts[o.index].Start
+			attempts[o.index].Err = o.err
+
+			if o.err == nil {
+				h.cancelLosers(attempts, finished, begin, outcomes, pending)
+				return &Result{Conn: o.conn, Attempts: attempts[:next], Winner: o.index}, nil
+			}
+
+			// Failed: don't wait for the delay, start the next attempt right away
+			errs = append(errs, fmt.Errorf("%s: %w", addrs[o.index], o.err))
+			if next < len(addrs) {
+				startNext()
+				armTimer()
+			}
+		}
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// Func cancelLosers - mark unfinished attempts as canceled and close
+// connections that manage to establish after the race is over
+func (h *HappyEyeballs) cancelLosers(
+	attempts []Attempt,
+	finished []bool,
+	begin time.Time,
+	outcomes <-chan outcome,
+	pending int,
+) {
+	for i := range attempts {
+		if attempts[i].Address != "" && !finished[i] {
+			attempts[i].Duration = time.Since(begin) - attempts[i].Start
+			attempts[i].Err = context.Canceled
+		}
+	}
+
+	// The race context is canceled by the caller, losers finish shortly
+	go func() {
+		for ; pending > 0; pending-- {
+			if o := <-outcomes; o.conn != nil {
+				_ = o.conn.Close()
+			}
+		}
+	}()
+}
@@ -0,0 +1,190 @@
+package dialer
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHappyEyeballsStaggeredStart(t *testing.T) {
+	// Listener for the address that should win the race
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// Accept connections in the background
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first address hangs before connecting
+	slow := net.JoinHostPort("127.0.0.2", port)
+	fast := listener.Addr().String()
+
+	h := HappyEyeballs{
+		AttemptDelay: 50 * time.Millisecond,
+		Dialer: &net.Dialer{
+			Control: func(_, address string, _ syscall.RawConn) error {
+				if address == slow {
+					time.Sleep(500 * time.Millisecond)
+				}
+				return nil
+			},
+		},
+	}
+
+	result, err := h.race(context.Background(), "tcp", []string{slow, fast})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Conn.Close()
+
+	// The second attempt wins
+	if result.Winner != 1 {
+		t.Fatalf("expected attempt 1 to win; actual %d", result.Winner)
+	}
+
+	// It started after the attempt delay, not right after the failure
+	if start := result.Attempts[1].Start; start < h.AttemptDelay {
+		t.Errorf("second attempt started too early: %s", start)
+	}
+
+	// The loser was canceled
+	if err := result.Attempts[0].Err; err != context.Canceled {
+		t.Errorf("expected canceled loser; actual %v", err)
+	}
+
+	for i, a := range result.Attempts {
+		t.Logf("%d: %s started at %s, took %s (%v)", i, a.Address, a.Start, a.Duration, a.Err)
+	}
+}
+
+func TestHappyEyeballsFailover(t *testing.T) {
+	// Listener for the only reachable address
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}()
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nobody listens on the first address, so it's refused immediately
+	refused := net.JoinHostPort("127.0.0.2", port)
+
+	// A long delay shows that a failure starts the next attempt right away
+	h := HappyEyeballs{AttemptDelay: time.Minute}
+
+	result, err := h.race(context.Background(), "tcp", []string{refused, listener.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Conn.Close()
+
+	if result.Winner != 1 {
+		t.Fatalf("expected attempt 1 to win; actual %d", result.Winner)
+	}
+
+	if result.Attempts[0].Err == nil {
+		t.Error("expected the first attempt to fail")
+	}
+
+	if start := result.Attempts[1].Start; start > time.Second {
+		t.Errorf("second attempt waited for the delay: %s", start)
+	}
+}
+
+func TestHappyEyeballsAllFail(t *testing.T) {
+	// Grab a free port and release it, so that nobody listens there
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	h := HappyEyeballs{AttemptDelay: 10 * time.Millisecond}
+
+	conn, err := h.DialContext(context.Background(), "tcp", addr)
+	if err == nil {
+		conn.Close()
+		t.Fatal("expected dial to fail")
+	}
+	t.Log(err)
+}
+
+func TestInterleave(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("10.0.0.1"),
+		net.ParseIP("10.0.0.2"),
+		net.ParseIP("::1"),
+		net.ParseIP("10.0.0.3"),
+		net.ParseIP("::2"),
+	}
+
+	expected := []string{"::1", "10.0.0.1", "::2", "10.0.0.2", "10.0.0.3"}
+
+	actual := interleave(ips)
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d IPs; actual %d", len(expected), len(actual))
+	}
+
+	for i := range expected {
+		if actual[i].String() != expected[i] {
+			t.Errorf("%d: expected %s; actual %s", i, expected[i], actual[i])
+		}
+	}
+}
+
+func TestHappyEyeballsHTTPTransport(t *testing.T) {
+	// Plain HTTP server to talk to
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+	))
+	defer ts.Close()
+
+	// Plug the dialer into the transport
+	h := &HappyEyeballs{}
+	client := &http.Client{
+		Transport: &http.Transport{DialContext: h.DialContext},
+	}
+
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected status %d; actual %d", http.StatusNoContent, resp.StatusCode)
+	}
+}
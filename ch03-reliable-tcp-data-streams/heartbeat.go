@@ -0,0 +1,219 @@
+package ch3
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+const defaultHeartbeatTimeout = 3 * defaultPingInterval
+
+// Frame layout (heartbeats and application messages share the stream,
+// so they're told apart by the type, never by the payload):
+//
+//	| type (1) | length (4) | payload (length) |
+const (
+	frameHeaderSize = 1 + 4
+	maxFrameSize    = 1 << 20
+)
+
+// Frame types
+const (
+	framePing byte = iota + 1
+	framePong
+	frameMessage
+)
+
+var (
+	errFrameTooLarge = errors.New("heartbeat: frame too large")
+	errFrameType     = errors.New("heartbeat: unknown frame type")
+)
+
+// Heartbeat keeps track of the peer on the other side of a connection:
+// it pings the peer with Ping, answers the peer's pings with pongs,
+// and declares the peer dead if nothing arrives within Timeout.
+//
+// Any incoming traffic (not only pongs) proves that the peer is alive,
+// so it pushes the read deadline forward and postpones the next ping.
+//
+// Heartbeat owns the connection: both peers have to run it, and messages
+// are sent with Write and received with OnMessage, framed along with
+// the heartbeats (a message split across reads is put back together).
+type Heartbeat struct {
+	// Connection to the peer
+	Conn net.Conn
+	// Time to wait for any traffic before declaring the peer dead
+	// (3 default ping intervals if not set)
+	Timeout time.Duration
+	// Channel to reset the ping interval, passed to Ping as is
+	// (created internally if not set)
+	Reset chan time.Duration
+	// Called when the peer goes dead (false) or comes back (true)
+	OnLiveness func(alive bool)
+	// Called for every message the peer sends with Write
+	// (msg is only valid until the callback returns)
+	OnMessage func(msg []byte)
+}
+
+// Func Run - ping the peer and watch its liveness until the context is canceled
+// or the connection fails (nil is returned in the former case)
+func (h *Heartbeat) Run(ctx context.Context) error {
+	// Set defaults
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultHeartbeatTimeout
+	}
+
+	reset := h.Reset
+	if reset == nil {
+		reset = make(chan time.Duration, 1)
+	}
+
+	// Stop pinging when done
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go Ping(ctx, frameWriter{h.Conn, framePing}, reset)
+
+	// Unblock the read on cancel by moving the deadline to the past
+	go func() {
+		<-ctx.Done()
+		_ = h.Conn.SetReadDeadline(time.Now())
+	}()
+
+	// The peer is considered alive right after connecting
+	alive := true
+	buf := make([]byte, 1024)
+	// Received data not making up a whole frame yet
+	var pending []byte
+
+	for {
+		// Give the peer another timeout to say something
+		err := h.Conn.SetReadDeadline(time.Now().Add(timeout))
+		if err != nil {
+			return err
+		}
+
+		// Canceled before the deadline was moved forward (e.g., in OnMessage):
+		// the past deadline set on cancel is gone, so the read wouldn't see it.
+		// Canceled after this check, the deadline is moved to the past again.
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		n, err := h.Conn.Read(buf)
+
+		// Stopped by the caller
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			// Not a timeout - the connection is broken
+			var nErr net.Error
+			if !errors.As(err, &nErr) || !nErr.Timeout() {
+				return err
+			}
+
+			// Timeout - the peer is dead, but keep waiting for it to come back
+			if alive {
+				alive = false
+				h.notify(alive)
+			}
+			continue
+		}
+
+		// Any traffic means the peer is alive
+		if !alive {
+			alive = true
+			h.notify(alive)
+		}
+
+		// Postpone the next ping (without overriding its interval)
+		select {
+		case reset <- 0:
+		default:
+		}
+
+		rest, err := h.handle(append(pending, buf[:n]...))
+		if err != nil {
+			return err
+		}
+		pending = append(pending[:0], rest...)
+	}
+}
+
+// Func handle - answer pings and pass messages to the callback for every
+// whole frame in data, return what's left of an incomplete one
+func (h *Heartbeat) handle(data []byte) ([]byte, error) {
+	// Several frames can arrive in a single read
+	for len(data) >= frameHeaderSize {
+		size := binary.BigEndian.Uint32(data[1:frameHeaderSize])
+		if size > maxFrameSize {
+			return nil, errFrameTooLarge
+		}
+		if uint32(len(data)-frameHeaderSize) < size {
+			break
+		}
+
+		typ, payload := data[0], data[frameHeaderSize:frameHeaderSize+int(size)]
+		data = data[frameHeaderSize+int(size):]
+
+		switch typ {
+		case framePing:
+			if _, err := (frameWriter{h.Conn, framePong}).Write(payload); err != nil {
+				return nil, err
+			}
+		case framePong:
+		case frameMessage:
+			if h.OnMessage != nil {
+				h.OnMessage(payload)
+			}
+		default:
+			return nil, errFrameType
+		}
+	}
+
+	return data, nil
+}
+
+// Func Write - send msg to the peer's OnMessage, safe to call
+// concurrently with Run
+func (h *Heartbeat) Write(msg []byte) (int, error) {
+	return frameWriter{h.Conn, frameMessage}.Write(msg)
+}
+
+// Func notify - report a liveness change if anyone is interested
+func (h *Heartbeat) notify(alive bool) {
+	if h.OnLiveness != nil {
+		h.OnLiveness(alive)
+	}
+}
+
+// struct frameWriter - writes every buffer as a single frame of the type
+// (in a single Write, so frames of concurrent writers don't interleave)
+type frameWriter struct {
+	w   io.Writer
+	typ byte
+}
+
+// Func Write
+func (w frameWriter) Write(b []byte) (int, error) {
+	if len(b) > maxFrameSize {
+		return 0, errFrameTooLarge
+	}
+
+	frame := make([]byte, frameHeaderSize+len(b))
+	frame[0] = w.typ
+	binary.BigEndian.PutUint32(frame[1:frameHeaderSize], uint32(len(b)))
+	copy(frame[frameHeaderSize:], b)
+
+	if _, err := w.w.Write(frame); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
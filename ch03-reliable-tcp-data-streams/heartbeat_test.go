@@ -0,0 +1,323 @@
+package ch3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatDeadPeer(t *testing.T) {
+	// Start a listener that accepts a connection
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// Channels for the heartbeat results
+	liveness := make(chan bool, 10)
+	done := make(chan error, 1)
+
+	go func() {
+		// Accept one connection
+		conn, err := listener.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+
+		// Ping every 100 ms, declare the peer dead after 300 ms of silence
+		reset := make(chan time.Duration, 1)
+		reset <- 100 * time.Millisecond
+
+		h := Heartbeat{
+			Conn:       conn,
+			Timeout:    300 * time.Millisecond,
+			Reset:      reset,
+			OnLiveness: func(alive bool) { liveness <- alive },
+		}
+		done <- h.Run(context.Background())
+	}()
+
+	// Dial the server
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Read pings, but don't answer them
+	buf := make([]byte, frameHeaderSize+4)
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := testFrame(t, framePing, "ping"); !bytes.Equal(buf, expected) {
+		t.Fatalf("expected %q; actual %q", expected, buf)
+	}
+
+	// The server should declare us dead
+	select {
+	case alive := <-liveness:
+		if alive {
+			t.Fatal("expected peer to be dead")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("peer wasn't declared dead")
+	}
+
+	// Answer with a pong to come back to life
+	_, err = conn.Write(testFrame(t, framePong, "ping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case alive := <-liveness:
+		if !alive {
+			t.Fatal("expected peer to be alive")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("peer wasn't declared alive")
+	}
+
+	// Closing the connection stops the heartbeat with an error
+	// (EOF or reset, since we left some pings unread)
+	_ = conn.Close()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected connection error")
+		}
+		t.Log(err)
+	case <-time.After(time.Second):
+		t.Fatal("heartbeat didn't stop")
+	}
+}
+
+func TestHeartbeatPair(t *testing.T) {
+	// Start a listener that accepts a connection
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// Context to stop both heartbeats
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Any liveness change is a failure: both sides keep pinging each other
+	dead := make(chan struct{}, 2)
+	onLiveness := func(alive bool) {
+		if !alive {
+			dead <- struct{}{}
+		}
+	}
+
+	// Helper to run a heartbeat with short interval and timeout
+	done := make(chan error, 2)
+	run := func(conn net.Conn) {
+		reset := make(chan time.Duration, 1)
+		reset <- 50 * time.Millisecond
+
+		h := Heartbeat{
+			Conn:       conn,
+			Timeout:    200 * time.Millisecond,
+			Reset:      reset,
+			OnLiveness: onLiveness,
+		}
+		done <- h.Run(ctx)
+	}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+
+		run(conn)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	go run(conn)
+
+	// Let them talk for several timeouts
+	select {
+	case <-dead:
+		t.Fatal("peer declared dead")
+	case <-time.After(time.Second):
+	}
+
+	// Both heartbeats stop without errors on cancel
+	cancel()
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("heartbeat %d: %v", i, err)
+		}
+	}
+}
+
+func TestHeartbeatCancelInMessage(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	// Drain the pings
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			if _, err := server.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel while handling a message and give the cancel goroutine time
+	// to move the deadline before the loop moves it forward again
+	h := Heartbeat{
+		Conn:    client,
+		Timeout: time.Minute,
+		OnMessage: func([]byte) {
+			cancel()
+			time.Sleep(50 * time.Millisecond)
+		},
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- h.Run(ctx) }()
+
+	if _, err := server.Write(testFrame(t, frameMessage, "hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected nil; actual %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("heartbeat didn't stop")
+	}
+}
+
+// Helper to encode a frame the way Heartbeat sends it
+func testFrame(t *testing.T, typ byte, payload string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, err := (frameWriter{&buf, typ}).Write([]byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestHeartbeatMessages(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messages := make(chan string, 10)
+	h := Heartbeat{
+		Conn:      client,
+		Timeout:   time.Minute,
+		OnMessage: func(msg []byte) { messages <- string(msg) },
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- h.Run(ctx) }()
+
+	// Collect what the heartbeat sends back
+	replies := make(chan []byte, 10)
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := server.Read(buf)
+			if err != nil {
+				return
+			}
+			replies <- bytes.Clone(buf[:n])
+		}
+	}()
+
+	// Messages looking like heartbeats are delivered as is
+	// and a frame split across writes is put back together
+	ping := testFrame(t, framePing, "ping")
+	writes := [][]byte{
+		testFrame(t, frameMessage, "pingpong"),
+		testFrame(t, frameMessage, "pong"),
+		ping[:2],
+		ping[2:],
+	}
+	split := testFrame(t, frameMessage, "split")
+	writes = append(writes, split[:3], split[3:])
+
+	for _, b := range writes {
+		if _, err := server.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, expected := range []string{"pingpong", "pong", "split"} {
+		select {
+		case actual := <-messages:
+			if actual != expected {
+				t.Errorf("expected %q; actual %q", expected, actual)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %q not delivered", expected)
+		}
+	}
+
+	// Only the real ping is answered
+	select {
+	case actual := <-replies:
+		if expected := testFrame(t, framePong, "ping"); !bytes.Equal(actual, expected) {
+			t.Errorf("expected %q; actual %q", expected, actual)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ping not answered")
+	}
+	select {
+	case actual := <-replies:
+		t.Errorf("expected a single pong; actual %q", actual)
+	case msg := <-messages:
+		t.Errorf("expected no more messages; actual %q", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Messages sent with Write arrive framed
+	go func() { _, _ = h.Write([]byte("ping")) }()
+	select {
+	case actual := <-replies:
+		if expected := testFrame(t, frameMessage, "ping"); !bytes.Equal(actual, expected) {
+			t.Errorf("expected %q; actual %q", expected, actual)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not sent")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("expected nil; actual %v", err)
+	}
+}
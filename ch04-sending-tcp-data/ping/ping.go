@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"net"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -14,15 +22,27 @@ var (
 	count = flag.Int("c", 3, "number of pings: <= 0 means forever")
 	// Interval between pings
 	interval = flag.Duration("i", time.Second, "inteval between pings")
-	// Connection timeout
+	// Probe timeout (all phases together)
 	timeout = flag.Duration("W", 5*time.Second, "time to wait for a reply")
+	// Output format
+	jsonOutput = flag.Bool("json", false, "print results as JSON lines")
+	// IP version selection
+	ipv4 = flag.Bool("4", false, "use IPv4 only")
+	ipv6 = flag.Bool("6", false, "use IPv6 only")
+	// TLS handshake phase options
+	useTLS     = flag.Bool("tls", false, "do a TLS handshake after connecting")
+	serverName = flag.String("sni", "", "TLS server name (defaults to the target host)")
+	caFile     = flag.String("ca", "", "PEM file with trusted CA certificates (defaults to the system pool)")
+	// Application phase options (Go escape sequences like \r\n are allowed)
+	send   = flag.String("send", "", "payload to send after connecting")
+	expect = flag.String("expect", "", "response expected from the target")
 )
 
 // Init function - called before main
 func init() {
 	// Initialize usage function (print description and default values)
 	flag.Usage = func() {
-		fmt.Printf("Usage: %s [options] host:port\nOptions:\n", os.Args[0])
+		fmt.Printf("Usage: %s [options] host:port...\nOptions:\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 }
@@ -32,54 +52,262 @@ func main() {
 	flag.Parse()
 
 	// If not enough arguments: print warning, show usage, and exit
-	if flag.NArg() <= 1 {
+	if flag.NArg() == 0 {
 		fmt.Printf("host:port is required\n\n")
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(2)
 	}
 
-	// String for address to ping to
-	target := flag.Args()[1]
+	// Pick the network depending on the IP version flags
+	network, err := selectNetwork(*ipv4, *ipv6)
+	if err != nil {
+		fmt.Printf("%v\n\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Prepare optional phases
+	tlsConfig, err := newTLSConfig(*useTLS, *serverName, *caFile)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	sendPayload, err := unescape(*send)
+	if err != nil {
+		fmt.Printf("-send: %v\n", err)
+		os.Exit(2)
+	}
+
+	expectPayload, err := unescape(*expect)
+	if err != nil {
+		fmt.Printf("-expect: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Stop pinging on Ctrl+C (or termination), the summary is printed anyway
+	ctx, stop := signal.NotifyContext(
+		context.Background(),
+		os.Interrupt,
+		syscall.SIGTERM,
+	)
+	defer stop()
+
+	// All positional arguments are targets
+	targets := uniqueTargets(flag.Args())
+
 	// Show aknowledge message
-	fmt.Println("PING", target)
-
-	// If no count limit, warn that user should send interrupt to exit
-	if *count <= 0 {
-		fmt.Println("Press CTRL+C to exit.")
-	}
-
-	// Ping counter (0 initial value)
-	msg := 0
-
-	// Loop if either ping endlessly or count exhausted
-	for (*count <= 0) || (msg < *count) {
-		// Increment try count
-		msg++
-		// Indicate try
-		fmt.Print(msg, " ")
-
-		// Start measuring time from now to end of dial try
-		start := time.Now()
-		// Dial with timeout specified
-		c, err := net.DialTimeout("tcp", target, *timeout)
-		// Stop measuring time
-		dur := time.Since(start)
-
-		// If any error
-		if err != nil {
-			// Print that we've failed
-			fmt.Printf("faile in %s: %v\n", dur, err)
-			// Cast error to network error, exit if it's not temporary
-			if nErr, ok := err.(net.Error); !ok || !nErr.Temporary() {
-				os.Exit(1)
+	if !*jsonOutput {
+		for _, target := range targets {
+			fmt.Println("PING", target)
+		}
+
+		// If no count limit, warn that user should send interrupt to exit
+		if *count <= 0 {
+			fmt.Println("Press CTRL+C to exit.")
+		}
+	}
+
+	// Ping the targets and print the results
+	allStats := run(ctx, targets, pinger{
+		network:   network,
+		count:     *count,
+		interval:  *interval,
+		timeout:   *timeout,
+		tlsConfig: tlsConfig,
+		send:      sendPayload,
+		expect:    expectPayload,
+	})
+	printSummary(allStats)
+
+	os.Exit(exitCode(allStats))
+}
+
+// Func exitCode - 1 if any of the targets never replied, 0 otherwise;
+// targets interrupted before the first probe have no result, not a failure
+func exitCode(allStats []*stats) int {
+	for _, s := range allStats {
+		if s.sent > 0 && s.received == 0 {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// Func selectNetwork - network name for the IP version flags
+func selectNetwork(ipv4, ipv6 bool) (string, error) {
+	switch {
+	case ipv4 && ipv6:
+		return "", fmt.Errorf("-4 and -6 are mutually exclusive")
+	case ipv4:
+		return "tcp4", nil
+	case ipv6:
+		return "tcp6", nil
+	default:
+		return "tcp", nil
+	}
+}
+
+// Func newTLSConfig - TLS configuration for the handshake phase
+// (nil if the phase is disabled)
+func newTLSConfig(enabled bool, serverName, caFile string) (*tls.Config, error) {
+	if !enabled {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	// Use the system pool unless a CA file is given
+	if caFile == "" {
+		return config, nil
+	}
+
+	// Read the CA certificates (e.g., the ones generated in ch11)
+	cert, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read CA file: %v", err)
+	}
+
+	// Create a pool and restrict trusted CAs to it
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(cert) {
+		return nil, fmt.Errorf("no certificates found in %q", caFile)
+	}
+	config.RootCAs = pool
+
+	return config, nil
+}
+
+// Func unescape - interpret Go escape sequences in a CLI payload
+// (nil for an empty string, meaning no payload)
+func unescape(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	// Escape bare double quotes, which would end the Go string literal
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			// Keep escape sequences (including \") as they are
+			quoted.WriteByte('\\')
+			if i+1 < len(s) {
+				i++
+				quoted.WriteByte(s[i])
 			}
-			// Else close connection and print duration
-		} else {
-			_ = c.Close()
-			fmt.Print(dur)
+		case '"':
+			quoted.WriteString(`\"`)
+		default:
+			quoted.WriteByte(s[i])
 		}
+	}
+	quoted.WriteByte('"')
 
-		// Sleep for time specified in interval
-		time.Sleep(*interval)
+	unquoted, err := strconv.Unquote(quoted.String())
+	if err != nil {
+		return nil, err
 	}
+
+	return []byte(unquoted), nil
+}
+
+// Func uniqueTargets - drop repeated targets keeping the order
+func uniqueTargets(args []string) []string {
+	seen := make(map[string]struct{}, len(args))
+	targets := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		if _, ok := seen[arg]; ok {
+			continue
+		}
+
+		seen[arg] = struct{}{}
+		targets = append(targets, arg)
+	}
+
+	return targets
+}
+
+// Func run - ping all targets concurrently using the pinger template,
+// print every probe and return the per-target statistics
+// (in the order of targets)
+func run(ctx context.Context, targets []string, template pinger) []*stats {
+	// Channel to collect probe results from all pingers
+	results := make(chan probe)
+
+	// Spin off a pinger per target
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+
+			p := template
+			p.target = target
+			p.run(ctx, results)
+		}(target)
+	}
+
+	// Close the results channel once all pingers are done
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Prepare statistics for every target
+	allStats := make([]*stats, 0, len(targets))
+	byTarget := make(map[string]*stats, len(targets))
+	for _, target := range targets {
+		s := &stats{target: target}
+		allStats = append(allStats, s)
+		byTarget[target] = s
+	}
+
+	// Print and account results in a single goroutine,
+	// so that the output doesn't interleave
+	for p := range results {
+		printProbe(p)
+		byTarget[p.target].add(p)
+	}
+
+	return allStats
+}
+
+// Func printProbe - print a probe result in the selected format
+func printProbe(p probe) {
+	if *jsonOutput {
+		printJSON(p.toJSON())
+		return
+	}
+
+	fmt.Println(p)
+}
+
+// Func printSummary - print statistics of all targets in the selected format
+func printSummary(allStats []*stats) {
+	for _, s := range allStats {
+		if *jsonOutput {
+			printJSON(s.toJSON())
+			continue
+		}
+
+		fmt.Printf("\n%s", s)
+	}
+}
+
+// Func printJSON - print a value as a single JSON line
+func printJSON(v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	fmt.Println(string(b))
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSelectNetwork(t *testing.T) {
+	testCases := []struct {
+		ipv4, ipv6 bool
+		expected   string
+		err        bool
+	}{
+		{false, false, "tcp", false},
+		{true, false, "tcp4", false},
+		{false, true, "tcp6", false},
+		{true, true, "", true},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := selectNetwork(testCase.ipv4, testCase.ipv6)
+		if (err != nil) != testCase.err {
+			t.Errorf("-4=%t -6=%t: unexpected error %v", testCase.ipv4, testCase.ipv6, err)
+		}
+		if actual != testCase.expected {
+			t.Errorf("-4=%t -6=%t: expected %q; actual %q", testCase.ipv4, testCase.ipv6, testCase.expected, actual)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []byte
+		err      bool
+	}{
+		// empty means no payload rather than an empty one
+		{``, nil, false},
+		{`PING`, []byte("PING"), false},
+		{`HEAD / HTTP/1.0\r\n\r\n`, []byte("HEAD / HTTP/1.0\r\n\r\n"), false},
+		{`\x00\x01é`, []byte("\x00\x01é"), false},
+		{`say "hi"`, []byte(`say "hi"`), false},
+		{`tab\t`, []byte("tab\t"), false},
+		{`\"quoted\"`, []byte(`"quoted"`), false},
+		{`bad\q`, nil, true},
+		{`trailing\`, nil, true},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := unescape(testCase.input)
+		if (err != nil) != testCase.err {
+			t.Errorf("%q: unexpected error %v", testCase.input, err)
+		}
+		if !bytes.Equal(actual, testCase.expected) || (actual == nil) != (testCase.expected == nil) {
+			t.Errorf("%q: expected %q; actual %q", testCase.input, testCase.expected, actual)
+		}
+	}
+}
+
+func TestUniqueTargets(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{}, []string{}},
+		{[]string{"a:1"}, []string{"a:1"}},
+		{[]string{"a:1", "b:2", "a:1", "c:3", "b:2"}, []string{"a:1", "b:2", "c:3"}},
+		// the same host on another port is another target
+		{[]string{"a:1", "a:2"}, []string{"a:1", "a:2"}},
+	}
+
+	for _, testCase := range testCases {
+		if actual := uniqueTargets(testCase.args); !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("%v: expected %v; actual %v", testCase.args, testCase.expected, actual)
+		}
+	}
+}
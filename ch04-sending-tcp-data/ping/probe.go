@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Struct phases - time spent in each step of a probe
+// (zero if the step was skipped)
+type phases struct {
+	// Resolving the host name
+	dns time.Duration
+	// Establishing the TCP connection
+	connect time.Duration
+	// TLS handshake
+	tls time.Duration
+	// Sending the payload and receiving the response
+	app time.Duration
+}
+
+// Func String - phase breakdown, similar to curl -w
+func (ph phases) String() string {
+	parts := make([]string, 0, 4)
+
+	for _, phase := range []struct {
+		name string
+		dur  time.Duration
+	}{
+		{"dns", ph.dns},
+		{"connect", ph.connect},
+		{"tls", ph.tls},
+		{"app", ph.app},
+	} {
+		if phase.dur > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%s", phase.name, phase.dur))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Struct probe - result of a single ping
+type probe struct {
+	// Target address and sequence number of the probe
+	target string
+	seq    int
+	// Resolved address which answered (empty if none did)
+	ip string
+	// Total time of the probe
+	rtt time.Duration
+	// Per-phase breakdown of the total time
+	phases phases
+	// Error if the probe failed
+	err error
+}
+
+// Func String - human-readable probe result
+func (p probe) String() string {
+	if p.err != nil {
+		return fmt.Sprintf("%s: seq=%d failed in %s: %v", p.target, p.seq, p.rtt, p.err)
+	}
+
+	return fmt.Sprintf("%s: seq=%d ip=%s %s total=%s", p.target, p.seq, p.ip, p.phases, p.rtt)
+}
+
+// Struct probeJSON - machine-readable probe result
+type probeJSON struct {
+	Type      string  `json:"type"`
+	Target    string  `json:"target"`
+	Seq       int     `json:"seq"`
+	IP        string  `json:"ip,omitempty"`
+	DNSMs     float64 `json:"dns_ms,omitempty"`
+	ConnectMs float64 `json:"connect_ms,omitempty"`
+	TLSMs     float64 `json:"tls_ms,omitempty"`
+	AppMs     float64 `json:"app_ms,omitempty"`
+	RTTMs     float64 `json:"rtt_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Func toJSON - convert probe to its JSON representation
+func (p probe) toJSON() probeJSON {
+	j := probeJSON{
+		Type:      "probe",
+		Target:    p.target,
+		Seq:       p.seq,
+		IP:        p.ip,
+		DNSMs:     milliseconds(p.phases.dns),
+		ConnectMs: milliseconds(p.phases.connect),
+		TLSMs:     milliseconds(p.phases.tls),
+		AppMs:     milliseconds(p.phases.app),
+		RTTMs:     milliseconds(p.rtt),
+	}
+
+	if p.err != nil {
+		j.Error = p.err.Error()
+	}
+
+	return j
+}
+
+// Struct pinger - pings a single target
+type pinger struct {
+	// Network to dial: tcp, tcp4 or tcp6
+	network string
+	// Target address
+	target string
+	// Number of probes (<= 0 means forever)
+	count int
+	// Interval between probes
+	interval time.Duration
+	// Time to wait for the whole probe
+	timeout time.Duration
+	// TLS configuration (nil means plain TCP)
+	tlsConfig *tls.Config
+	// Payload to send after connecting (nil means don't send)
+	send []byte
+	// Expected response (nil means any response)
+	expect []byte
+}
+
+// Func run - ping the target until count is exhausted or context is canceled,
+// sending every probe result to the channel
+func (p pinger) run(ctx context.Context, results chan<- probe) {
+	for seq := 1; p.count <= 0 || seq <= p.count; seq++ {
+		// Do the probe
+		result := p.probe(ctx, seq)
+
+		// Canceled probes don't count as lost: the user just stopped us
+		if ctx.Err() != nil {
+			return
+		}
+
+		results <- result
+
+		// Don't wait after the last probe
+		if p.count > 0 && seq == p.count {
+			return
+		}
+
+		// Wait for the interval or cancellation
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.interval):
+		}
+	}
+}
+
+// Func probe - go through all configured phases once and time them
+func (p pinger) probe(ctx context.Context, seq int) probe {
+	// The timeout covers all phases of the probe
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	result := probe{target: p.target, seq: seq}
+
+	start := time.Now()
+	result.err = p.doProbe(ctx, &result)
+	result.rtt = time.Since(start)
+
+	return result
+}
+
+// Func doProbe - run the phases one by one, filling in their durations
+// and the address which answered
+func (p pinger) doProbe(ctx context.Context, result *probe) error {
+	ph := &result.phases
+
+	host, port, err := net.SplitHostPort(p.target)
+	if err != nil {
+		return err
+	}
+
+	// DNS phase: resolve the host unless it's an IP literal already
+	ips, err := p.resolve(ctx, host, &ph.dns)
+	if err != nil {
+		return fmt.Errorf("dns: %w", err)
+	}
+
+	// Connect phase: dial the resolved addresses until one answers
+	start := time.Now()
+	conn, err := p.dial(ctx, ips, port)
+	ph.connect = time.Since(start)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	result.ip, _, _ = net.SplitHostPort(conn.RemoteAddr().String())
+	// Close connection at scope exit
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// Make all reads and writes respect the probe timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// TLS phase: do the handshake on top of the connection
+	if p.tlsConfig != nil {
+		config := p.tlsConfig.Clone()
+		// Send the host name as SNI unless specified explicitly
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+
+		tlsConn := tls.Client(conn, config)
+		start = time.Now()
+		err = tlsConn.HandshakeContext(ctx)
+		ph.tls = time.Since(start)
+		if err != nil {
+			return fmt.Errorf("handshake: %w", err)
+		}
+
+		conn = tlsConn
+	}
+
+	// Application phase: send the payload and/or wait for the response
+	if p.send != nil || p.expect != nil {
+		start = time.Now()
+		err = p.exchange(conn)
+		ph.app = time.Since(start)
+		if err != nil {
+			return fmt.Errorf("app: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Func resolve - look up all addresses of the host matching the network
+func (p pinger) resolve(ctx context.Context, host string, dur *time.Duration) ([]string, error) {
+	// Nothing to resolve for IP literals
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	// Map the dial network to the lookup network
+	network := "ip"
+	switch p.network {
+	case "tcp4":
+		network = "ip4"
+	case "tcp6":
+		network = "ip6"
+	}
+
+	start := time.Now()
+	ips, err := net.DefaultResolver.LookupIP(ctx, network, host)
+	*dur = time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, ip.String())
+	}
+
+	return addrs, nil
+}
+
+// Func dial - connect to the addresses in turn until one answers, like
+// net.Dial does: e.g., an AAAA record comes first but IPv6 is unreachable.
+// Each address gets an equal share of the remaining time, at least 2 s.
+func (p pinger) dial(ctx context.Context, ips []string, port string) (net.Conn, error) {
+	var (
+		d    net.Dialer
+		errs []error
+	)
+
+	for i, ip := range ips {
+		attemptCtx := ctx
+		if deadline, ok := ctx.Deadline(); ok {
+			share := time.Until(deadline) / time.Duration(len(ips)-i)
+			if share < 2*time.Second {
+				share = time.Until(deadline)
+			}
+
+			var cancel context.CancelFunc
+			attemptCtx, cancel = context.WithTimeout(ctx, share)
+			defer cancel()
+		}
+
+		conn, err := d.DialContext(attemptCtx, p.network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+
+		// No time left for the other addresses
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// Func exchange - write the payload (if any) and read the response,
+// checking it against the expected one (if any)
+func (p pinger) exchange(conn net.Conn) error {
+	// Some protocols greet first (SMTP, SSH), so sending is optional
+	if p.send != nil {
+		_, err := conn.Write(p.send)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Without expectations, any response will do
+	if p.expect == nil {
+		buf := make([]byte, 1024)
+		_, err := conn.Read(buf)
+		return err
+	}
+
+	// Otherwise read exactly as much as expected and compare
+	buf := make([]byte, len(p.expect))
+	_, err := io.ReadFull(conn, buf)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(buf, p.expect) {
+		return fmt.Errorf("expected response %q; actual %q", p.expect, buf)
+	}
+
+	return nil
+}
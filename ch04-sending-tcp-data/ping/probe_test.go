@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper to start a TLS echo server on 127.0.0.1, returning its port
+// and a client configuration trusting its certificate
+func testEchoServer(t *testing.T) (string, *tls.Config) {
+	t.Helper()
+
+	// Borrow the certificate of httptest (valid for example.com and 127.0.0.1)
+	ts := httptest.NewTLSServer(nil)
+	t.Cleanup(ts.Close)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:", ts.TLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	return port, &tls.Config{RootCAs: pool, ServerName: "example.com"}
+}
+
+func TestProbePhases(t *testing.T) {
+	port, tlsConfig := testEchoServer(t)
+
+	p := pinger{
+		network:   "tcp4",
+		timeout:   5 * time.Second,
+		tlsConfig: tlsConfig,
+		send:      []byte("PING\r\n"),
+		expect:    []byte("PING\r\n"),
+	}
+
+	// A host name goes through every phase
+	p.target = net.JoinHostPort("localhost", port)
+	result := p.probe(context.Background(), 1)
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	for name, dur := range map[string]time.Duration{
+		"dns":     result.phases.dns,
+		"connect": result.phases.connect,
+		"tls":     result.phases.tls,
+		"app":     result.phases.app,
+	} {
+		if dur <= 0 {
+			t.Errorf("expected %s phase to be reported; actual %s", name, result.phases)
+		}
+		if dur > result.rtt {
+			t.Errorf("expected %s phase within the total %s; actual %s", name, result.rtt, dur)
+		}
+	}
+	if actual := result.ip; actual != "127.0.0.1" {
+		t.Errorf("expected ip 127.0.0.1; actual %q", actual)
+	}
+
+	// An IP literal skips the DNS phase
+	p.target = net.JoinHostPort("127.0.0.1", port)
+	result = p.probe(context.Background(), 2)
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if actual := result.phases.dns; actual != 0 {
+		t.Errorf("expected no dns phase; actual %s", actual)
+	}
+}
+
+func TestProbeExchange(t *testing.T) {
+	port, tlsConfig := testEchoServer(t)
+
+	testCases := []struct {
+		send   string
+		expect string
+		err    string
+	}{
+		// the echoed payload is read back in full
+		{"PING\r\n", "PING\r\n", ""},
+		{strings.Repeat("x", 64*1024), strings.Repeat("x", 64*1024), ""},
+		// any response will do without expectations
+		{"hello", "", ""},
+		{"PING\r\n", "PONG\r\n", "expected response"},
+	}
+
+	for i, testCase := range testCases {
+		p := pinger{
+			network:   "tcp",
+			target:    net.JoinHostPort("127.0.0.1", port),
+			timeout:   5 * time.Second,
+			tlsConfig: tlsConfig,
+			send:      []byte(testCase.send),
+		}
+		if testCase.expect != "" {
+			p.expect = []byte(testCase.expect)
+		}
+
+		result := p.probe(context.Background(), i)
+		if testCase.err == "" && result.err != nil {
+			t.Errorf("%d: unexpected error %v", i, result.err)
+		}
+		if testCase.err != "" && (result.err == nil || !strings.Contains(result.err.Error(), testCase.err)) {
+			t.Errorf("%d: expected error with %q; actual %v", i, testCase.err, result.err)
+		}
+	}
+}
+
+func TestDialFallback(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	p := pinger{network: "tcp"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Nobody listens on 127.0.0.2, so the first address is refused
+	conn, err := p.dial(ctx, []string{"127.0.0.2", "127.0.0.1"}, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if actual := conn.RemoteAddr().String(); actual != listener.Addr().String() {
+		t.Errorf("expected %s; actual %s", listener.Addr(), actual)
+	}
+
+	// All the errors are reported if none answers
+	_, err = p.dial(ctx, []string{"127.0.0.2", "127.0.0.3"}, port)
+	if err == nil || !strings.Contains(err.Error(), "127.0.0.2") || !strings.Contains(err.Error(), "127.0.0.3") {
+		t.Errorf("expected errors of both addresses; actual %v", err)
+	}
+}
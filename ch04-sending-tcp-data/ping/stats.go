@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Struct stats - accumulated results of pinging a single target
+type stats struct {
+	// Target address
+	target string
+	// Number of probes sent and number of successful ones
+	sent     int
+	received int
+	// Extreme round-trip times
+	min time.Duration
+	max time.Duration
+	// Sum of round-trip times and sum of their squares
+	// (in float nanoseconds to avoid overflows), to compute avg and stddev
+	sum   float64
+	sumSq float64
+}
+
+// Func add - account for a probe result
+func (s *stats) add(p probe) {
+	s.sent++
+
+	// Failed probes only count as sent
+	if p.err != nil {
+		return
+	}
+
+	s.received++
+
+	// Update the extreme values (first success initializes both)
+	if s.received == 1 || p.rtt < s.min {
+		s.min = p.rtt
+	}
+	if p.rtt > s.max {
+		s.max = p.rtt
+	}
+
+	ns := float64(p.rtt)
+	s.sum += ns
+	s.sumSq += ns * ns
+}
+
+// Func loss - percentage of failed probes
+func (s *stats) loss() float64 {
+	if s.sent == 0 {
+		return 0
+	}
+
+	return 100 * float64(s.sent-s.received) / float64(s.sent)
+}
+
+// Func avg - mean round-trip time of successful probes
+func (s *stats) avg() time.Duration {
+	if s.received == 0 {
+		return 0
+	}
+
+	return time.Duration(s.sum / float64(s.received))
+}
+
+// Func stddev - population standard deviation of round-trip times
+func (s *stats) stddev() time.Duration {
+	if s.received == 0 {
+		return 0
+	}
+
+	n := float64(s.received)
+	mean := s.sum / n
+	// Variance can drop slightly below zero due to rounding
+	variance := math.Max(s.sumSq/n-mean*mean, 0)
+
+	return time.Duration(math.Sqrt(variance))
+}
+
+// Func String - human-readable summary, similar to the one of ping(8)
+func (s *stats) String() string {
+	summary := fmt.Sprintf(
+		"--- %s ping statistics ---\n"+
+			"%d probes sent, %d succeeded, %.1f%% loss\n",
+		s.target, s.sent, s.received, s.loss(),
+	)
+
+	// Round-trip times only make sense if anything succeeded
+	if s.received > 0 {
+		summary += fmt.Sprintf(
+			"rtt min/avg/max/stddev = %s/%s/%s/%s\n",
+			s.min, s.avg(), s.max, s.stddev(),
+		)
+	}
+
+	return summary
+}
+
+// Struct statsJSON - machine-readable summary
+type statsJSON struct {
+	Type        string  `json:"type"`
+	Target      string  `json:"target"`
+	Sent        int     `json:"sent"`
+	Received    int     `json:"received"`
+	LossPercent float64 `json:"loss_percent"`
+	MinMs       float64 `json:"min_ms"`
+	AvgMs       float64 `json:"avg_ms"`
+	MaxMs       float64 `json:"max_ms"`
+	StddevMs    float64 `json:"stddev_ms"`
+}
+
+// Func toJSON - convert stats to their JSON representation
+func (s *stats) toJSON() statsJSON {
+	return statsJSON{
+		Type:        "summary",
+		Target:      s.target,
+		Sent:        s.sent,
+		Received:    s.received,
+		LossPercent: s.loss(),
+		MinMs:       milliseconds(s.min),
+		AvgMs:       milliseconds(s.avg()),
+		MaxMs:       milliseconds(s.max),
+		StddevMs:    milliseconds(s.stddev()),
+	}
+}
+
+// Func milliseconds - duration as fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	failed := errors.New("connection refused")
+
+	testCases := []struct {
+		name   string
+		probes []probe
+		loss   float64
+		min    time.Duration
+		avg    time.Duration
+		max    time.Duration
+		stddev time.Duration
+	}{
+		{name: "no probes"},
+		{
+			name:   "all lost",
+			probes: []probe{{err: failed}, {err: failed}},
+			loss:   100,
+		},
+		{
+			name:   "single",
+			probes: []probe{{rtt: 5 * time.Millisecond}},
+			min:    5 * time.Millisecond,
+			avg:    5 * time.Millisecond,
+			max:    5 * time.Millisecond,
+		},
+		{
+			// failed probes don't affect the round-trip times
+			name: "some lost",
+			probes: []probe{
+				{rtt: 2 * time.Millisecond},
+				{rtt: time.Hour, err: failed},
+				{rtt: 4 * time.Millisecond},
+				{rtt: 4 * time.Millisecond},
+				{rtt: 4 * time.Millisecond},
+				{rtt: 5 * time.Millisecond},
+				{rtt: 5 * time.Millisecond},
+				{rtt: 7 * time.Millisecond},
+				{rtt: 9 * time.Millisecond},
+			},
+			loss:   100.0 / 9,
+			min:    2 * time.Millisecond,
+			avg:    5 * time.Millisecond,
+			max:    9 * time.Millisecond,
+			stddev: 2 * time.Millisecond,
+		},
+		{
+			// the first success sets the minimum even if it's the largest
+			name:   "decreasing",
+			probes: []probe{{err: failed}, {rtt: 3 * time.Second}, {rtt: time.Second}},
+			loss:   100.0 / 3,
+			min:    time.Second,
+			avg:    2 * time.Second,
+			max:    3 * time.Second,
+			stddev: time.Second,
+		},
+	}
+
+	for _, testCase := range testCases {
+		s := &stats{target: testCase.name}
+		for _, p := range testCase.probes {
+			s.add(p)
+		}
+
+		if actual := s.sent; actual != len(testCase.probes) {
+			t.Errorf("%s: expected %d sent; actual %d", testCase.name, len(testCase.probes), actual)
+		}
+		if actual := s.loss(); math.Abs(actual-testCase.loss) > 1e-9 {
+			t.Errorf("%s: expected loss %.2f%%; actual %.2f%%", testCase.name, testCase.loss, actual)
+		}
+		if actual := s.min; actual != testCase.min {
+			t.Errorf("%s: expected min %s; actual %s", testCase.name, testCase.min, actual)
+		}
+		if actual := s.avg(); actual != testCase.avg {
+			t.Errorf("%s: expected avg %s; actual %s", testCase.name, testCase.avg, actual)
+		}
+		if actual := s.max; actual != testCase.max {
+			t.Errorf("%s: expected max %s; actual %s", testCase.name, testCase.max, actual)
+		}
+		// allow for rounding of the float sums
+		if actual := s.stddev(); (actual - testCase.stddev).Abs() > time.Microsecond {
+			t.Errorf("%s: expected stddev %s; actual %s", testCase.name, testCase.stddev, actual)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		stats    []*stats
+		expected int
+	}{
+		{"all replied", []*stats{{sent: 3, received: 3}, {sent: 3, received: 1}}, 0},
+		{"one never replied", []*stats{{sent: 3, received: 3}, {sent: 3}}, 1},
+		// interrupted before the first probe: no result, not a failure
+		{"nothing sent", []*stats{{}, {}}, 0},
+		{"nothing sent to one", []*stats{{sent: 2, received: 2}, {}}, 0},
+	}
+
+	for _, testCase := range testCases {
+		if actual := exitCode(testCase.stats); actual != testCase.expected {
+			t.Errorf("%s: expected %d; actual %d", testCase.name, testCase.expected, actual)
+		}
+	}
+}
@@ -75,3 +75,16 @@ func decode(r io.Reader, p *Payload) (int64, error) {
 
 	return n, nil
 }
+
+// Func Encode - write the payload as a type-length-value frame
+func Encode(w io.Writer, p Payload) (int64, error) {
+	return encode(w, p)
+}
+
+// Func Decode - read a type-length-value frame
+func Decode(r io.Reader) (Payload, int64, error) {
+	var p Payload
+	n, err := decode(r, &p)
+
+	return p, n, err
+}
@@ -0,0 +1,105 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Announcer periodically announces a service to the group,
+// answers queries for it and says goodbye when stopped
+type Announcer struct {
+	Config  Config
+	Service Service
+	// Period between announcements, DefaultInterval if zero
+	Interval time.Duration
+	// Validity of an announcement, DefaultTTL if zero
+	TTL time.Duration
+}
+
+// Func Run - announce the service until the context is done
+func (a *Announcer) Run(ctx context.Context) error {
+	if a.Service.Name == "" || a.Service.Addr == "" {
+		return errors.New("discovery: service name and address are required")
+	}
+
+	interval := a.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ttl := a.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	t, err := a.Config.open()
+	if err != nil {
+		return err
+	}
+
+	announce := message{Type: typeAnnounce, Service: a.Service, TTL: ttl.Milliseconds()}
+	// Fail early, e.g., if the metadata doesn't fit into a datagram
+	if _, err := announce.MarshalBinary(); err != nil {
+		_ = t.close()
+		return err
+	}
+
+	// Answer queries in the background
+	queries := make(chan struct{}, 1)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- a.readQueries(t, queries)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	err = t.write(announce)
+	for err == nil {
+		select {
+		case <-ctx.Done():
+			// Let browsers forget the service right away
+			// instead of waiting for the TTL to expire
+			_ = t.write(message{Type: typeBye, Service: a.Service})
+			_ = t.close()
+			<-readErr
+
+			return nil
+		case err = <-readErr:
+			_ = t.close()
+			return err
+		case <-queries:
+			err = t.write(announce)
+		case <-ticker.C:
+			err = t.write(announce)
+		}
+	}
+
+	_ = t.close()
+	<-readErr
+
+	return err
+}
+
+// Func readQueries - signal queries matching the service until the socket is closed
+func (a *Announcer) readQueries(t *transport, queries chan<- struct{}) error {
+	buf := make([]byte, maxMessageSize)
+
+	for {
+		m, err := t.read(buf)
+		if err != nil {
+			return err
+		}
+
+		if m.Type != typeQuery || (m.Service.Name != "" && m.Service.Name != a.Service.Name) {
+			continue
+		}
+
+		// Non-blocking: one pending announcement answers any number of queries
+		select {
+		case queries <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"learn-network-programming/ch05-unreliable-udp-communication/discovery"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	group     = flag.String("g", discovery.DefaultGroup, "multicast group or broadcast address")
+	ifname    = flag.String("i", "", "interface name (system default if empty)")
+	loopback  = flag.Bool("loopback", false, "use the loopback interface only")
+	interval  = flag.Duration("interval", discovery.DefaultInterval, "announcement interval")
+	timeout   = flag.Duration("t", 5*time.Second, "resolve timeout")
+	browseFor = flag.Duration("d", 3*time.Second, "how long to browse")
+)
+
+// Func init
+func init() {
+	flag.Usage = func() {
+		name := filepath.Base(os.Args[0])
+		fmt.Fprintf(
+			flag.CommandLine.Output(),
+			"Usage:\n"+
+				"\t%[1]s [flags] announce <name> <address> [key=value...]\n"+
+				"\t%[1]s [flags] browse\n"+
+				"\t%[1]s [flags] resolve <name>\n",
+			name,
+		)
+		flag.PrintDefaults()
+	}
+}
+
+// Func main
+func main() {
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config := discovery.Config{Group: *group, Loopback: *loopback}
+	if *ifname != "" {
+		ifi, err := net.InterfaceByName(*ifname)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.Interface = ifi
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	switch {
+	case args[0] == "announce" && len(args) >= 3:
+		err = announce(ctx, config, args[1], args[2], args[3:])
+	case args[0] == "browse" && len(args) == 1:
+		err = browse(ctx, config)
+	case args[0] == "resolve" && len(args) == 2:
+		err = resolve(ctx, config, args[1])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Func announce - announce the service until interrupted
+func announce(ctx context.Context, config discovery.Config, name, addr string, meta []string) error {
+	service := discovery.Service{Name: name, Addr: addr, Meta: make(map[string]string)}
+	for _, kv := range meta {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("invalid metadata %q, expected key=value", kv)
+		}
+		service.Meta[k] = v
+	}
+
+	log.Printf("Announcing %v", service)
+	a := discovery.Announcer{Config: config, Service: service, Interval: *interval}
+	return a.Run(ctx)
+}
+
+// Func browse - print the services seen within the browse duration
+func browse(ctx context.Context, config discovery.Config) error {
+	b, err := config.Browse()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = b.Close() }()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(*browseFor):
+	}
+
+	for _, service := range b.Services() {
+		fmt.Println(service)
+	}
+
+	return nil
+}
+
+// Func resolve - print the address of an instance of the service
+func resolve(ctx context.Context, config discovery.Config, name string) error {
+	b, err := config.Browse()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = b.Close() }()
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	service, err := b.Resolve(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(service.Addr)
+	return nil
+}
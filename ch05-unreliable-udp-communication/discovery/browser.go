@@ -0,0 +1,169 @@
+package discovery
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Browser listens to the group and keeps track of the announced services,
+// forgetting them once their announcements expire
+type Browser struct {
+	t *transport
+
+	mu       sync.Mutex
+	services map[string]entry
+	// Closed and replaced whenever a service is announced
+	changed chan struct{}
+	// Why the browser stopped, if it did
+	err error
+	// Closed when the browser stops
+	done chan struct{}
+}
+
+// struct entry - cached announcement
+type entry struct {
+	service Service
+	expires time.Time
+}
+
+// Func Browse - join the group and ask all services to announce themselves
+func (c Config) Browse() (*Browser, error) {
+	t, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Browser{
+		t:        t,
+		services: make(map[string]entry),
+		changed:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go b.readLoop()
+
+	// Don't wait for the next periodic announcements
+	_ = t.write(message{Type: typeQuery})
+
+	return b, nil
+}
+
+// Func readLoop - update the cache until the socket is closed
+func (b *Browser) readLoop() {
+	buf := make([]byte, maxMessageSize)
+
+	for {
+		m, err := b.t.read(buf)
+		if err != nil {
+			b.mu.Lock()
+			b.err = err
+			b.mu.Unlock()
+
+			close(b.done)
+			return
+		}
+
+		b.mu.Lock()
+		switch m.Type {
+		case typeAnnounce:
+			b.services[m.Service.key()] = entry{service: m.Service, expires: time.Now().Add(m.ttl())}
+			close(b.changed)
+			b.changed = make(chan struct{})
+		case typeBye:
+			delete(b.services, m.Service.key())
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Func lookupLocked - live instances of the named service (all if the name is empty)
+// sorted by name and address, must be called under lock
+func (b *Browser) lookupLocked(name string) []Service {
+	now := time.Now()
+	var services []Service
+
+	for key, e := range b.services {
+		if now.After(e.expires) {
+			delete(b.services, key)
+			continue
+		}
+
+		if name == "" || e.service.Name == name {
+			services = append(services, e.service)
+		}
+	}
+
+	slices.SortFunc(services, func(a, b Service) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Addr, b.Addr))
+	})
+
+	return services
+}
+
+// Func Services - all live services
+func (b *Browser) Services() []Service {
+	return b.Lookup("")
+}
+
+// Func Lookup - live instances of the named service, without waiting
+func (b *Browser) Lookup(name string) []Service {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.lookupLocked(name)
+}
+
+// Func Resolve - wait for an instance of the named service,
+// querying the group until one is announced or the context is done
+func (b *Browser) Resolve(ctx context.Context, name string) (Service, error) {
+	// Queries may be lost as well
+	ticker := time.NewTicker(DefaultInterval)
+	defer ticker.Stop()
+
+	queried := false
+	for {
+		b.mu.Lock()
+		services := b.lookupLocked(name)
+		changed := b.changed
+		err := b.err
+		b.mu.Unlock()
+
+		if len(services) > 0 {
+			return services[0], nil
+		}
+
+		if err != nil {
+			return Service{}, err
+		}
+
+		if !queried {
+			_ = b.t.write(message{Type: typeQuery, Service: Service{Name: name}})
+			queried = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return Service{}, ctx.Err()
+		case <-b.done:
+		case <-changed:
+		case <-ticker.C:
+			queried = false
+		}
+	}
+}
+
+// Func Close - leave the group
+func (b *Browser) Close() error {
+	err := b.t.close()
+	<-b.done
+
+	return err
+}
+
+// Func Browse - Browse with the default config
+func Browse() (*Browser, error) {
+	return Config{}.Browse()
+}
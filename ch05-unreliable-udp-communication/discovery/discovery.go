@@ -0,0 +1,213 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Defaults for the zero Config, Announcer and Browser
+const (
+	// Organization-local scope multicast group (RFC 2365)
+	DefaultGroup = "239.255.42.99:9999"
+	// Period between announcements
+	DefaultInterval = time.Second
+	// How long an announcement is valid: a few lost datagrams
+	// don't make the service disappear
+	DefaultTTL = 3 * DefaultInterval
+)
+
+// Maximum size of an encoded message, keeps it within a single datagram
+const maxMessageSize = 1024
+
+// ErrNoLoopback is returned in the loopback mode if there's no loopback interface
+var ErrNoLoopback = errors.New("discovery: no loopback interface")
+
+// Service is an announced service instance
+type Service struct {
+	// Name of the service, not unique: a service can have many instances
+	Name string `json:"name"`
+	// Address clients connect to, e.g., "10.0.0.5:8080"
+	Addr string `json:"addr"`
+	// Optional metadata, e.g., version or protocol
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// Func key - identifies an instance of the service
+func (s Service) key() string { return s.Name + "\x00" + s.Addr }
+
+// Func String
+func (s Service) String() string {
+	if len(s.Meta) == 0 {
+		return fmt.Sprintf("%s at %s", s.Name, s.Addr)
+	}
+	return fmt.Sprintf("%s at %s %v", s.Name, s.Addr, s.Meta)
+}
+
+// Config selects the group and the interface, the zero value uses DefaultGroup
+// on the system default interface
+type Config struct {
+	// Multicast group ("239.255.42.99:9999", "[ff15::42]:9999")
+	// or broadcast address ("255.255.255.255:9999") with a port
+	Group string
+	// Interface to send and receive on, nil for the system default
+	Interface *net.Interface
+	// Send and receive on the loopback interface only, so that discovery
+	// works within a single host without a real LAN (e.g., in CI)
+	Loopback bool
+}
+
+// struct transport - receiving and sending sockets of a group member
+type transport struct {
+	recv  *net.UDPConn
+	send  *net.UDPConn
+	group *net.UDPAddr
+}
+
+// Func open - join the group and create the sending socket
+func (c Config) open() (*transport, error) {
+	address := c.Group
+	if address == "" {
+		address = DefaultGroup
+	}
+
+	group, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	network := "udp4"
+	if group.IP.To4() == nil {
+		network = "udp6"
+	}
+
+	ifi := c.Interface
+	if c.Loopback {
+		if ifi, err = loopbackInterface(); err != nil {
+			return nil, err
+		}
+	}
+
+	recv, err := listenGroup(network, ifi, group)
+	if err != nil {
+		return nil, err
+	}
+
+	send, err := dialGroup(network, ifi, c.Loopback, group)
+	if err != nil {
+		_ = recv.Close()
+		return nil, err
+	}
+
+	return &transport{recv: recv, send: send, group: group}, nil
+}
+
+// Func listenGroup - receiving socket: joins the multicast group,
+// or shares the port with other members for broadcasts
+func listenGroup(network string, ifi *net.Interface, group *net.UDPAddr) (*net.UDPConn, error) {
+	if group.IP.IsMulticast() {
+		return net.ListenMulticastUDP(network, ifi, group)
+	}
+
+	config := net.ListenConfig{Control: reuseAddr}
+	pc, err := config.ListenPacket(context.Background(), network, fmt.Sprintf(":%d", group.Port))
+	if err != nil {
+		return nil, err
+	}
+
+	return pc.(*net.UDPConn), nil
+}
+
+// Func dialGroup - sending socket, sends multicast datagrams through
+// the interface (if set) and loops them back to the local members
+func dialGroup(network string, ifi *net.Interface, loopback bool, group *net.UDPAddr) (*net.UDPConn, error) {
+	var laddr *net.UDPAddr
+	if loopback {
+		laddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}
+		if network == "udp6" {
+			laddr.IP = net.IPv6loopback
+		}
+	}
+
+	conn, err := net.ListenUDP(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if !group.IP.IsMulticast() {
+		return conn, nil
+	}
+
+	if network == "udp4" {
+		p := ipv4.NewPacketConn(conn)
+		err = p.SetMulticastLoopback(true)
+		if err == nil && ifi != nil {
+			err = p.SetMulticastInterface(ifi)
+		}
+	} else {
+		p := ipv6.NewPacketConn(conn)
+		err = p.SetMulticastLoopback(true)
+		if err == nil && ifi != nil {
+			err = p.SetMulticastInterface(ifi)
+		}
+	}
+
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// Func loopbackInterface - the first loopback interface that's up
+func loopbackInterface() (*net.Interface, error) {
+	ifis, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ifi := range ifis {
+		if ifi.Flags&net.FlagLoopback != 0 && ifi.Flags&net.FlagUp != 0 {
+			return &ifi, nil
+		}
+	}
+
+	return nil, ErrNoLoopback
+}
+
+// Func write - send the message to the group
+func (t *transport) write(m message) error {
+	b, err := m.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	_, err = t.send.WriteToUDP(b, t.group)
+	return err
+}
+
+// Func read - read the next valid message, skipping garbage
+func (t *transport) read(buf []byte) (message, error) {
+	for {
+		n, _, err := t.recv.ReadFromUDP(buf)
+		if err != nil {
+			return message{}, err
+		}
+
+		var m message
+		if err := m.UnmarshalBinary(buf[:n]); err == nil {
+			return m, nil
+		}
+	}
+}
+
+// Func close
+func (t *transport) close() error {
+	return errors.Join(t.recv.Close(), t.send.Close())
+}
@@ -0,0 +1,192 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// loopbackConfig - a group of its own for every test, on the loopback interface
+func loopbackConfig(t *testing.T) Config {
+	t.Helper()
+
+	// Borrow a free port
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	_ = pc.Close()
+
+	return Config{Group: fmt.Sprintf("239.255.42.99:%d", port), Loopback: true}
+}
+
+// announce - run the announcer until the test ends or the returned func is called
+func announce(t *testing.T, a *Announcer) context.CancelFunc {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() { errs <- a.Run(ctx) }()
+
+	stop := func() {
+		cancel()
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	t.Cleanup(func() {
+		select {
+		case <-ctx.Done():
+		default:
+			stop()
+		}
+	})
+
+	return stop
+}
+
+func TestMessageMarshalling(t *testing.T) {
+	expected := message{
+		Type:    typeAnnounce,
+		Service: Service{Name: "api", Addr: "127.0.0.1:8080", Meta: map[string]string{"v": "2"}},
+		TTL:     3000,
+	}
+
+	b, err := expected.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual message
+	if err := actual.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %+v; actual %+v", expected, actual)
+	}
+
+	for _, invalid := range []string{
+		`garbage`,
+		`{"type":"unknown"}`,
+		`{"type":"announce","service":{"name":"api","addr":"127.0.0.1:8080"}}`,
+		`{"type":"bye","service":{"name":"api"}}`,
+	} {
+		if err := actual.UnmarshalBinary([]byte(invalid)); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	config := loopbackConfig(t)
+
+	service := Service{Name: "api", Addr: "127.0.0.1:8080", Meta: map[string]string{"v": "2"}}
+	// Long interval: the browser has to query
+	announce(t, &Announcer{Config: config, Service: service, Interval: time.Hour})
+	announce(t, &Announcer{Config: config, Service: Service{Name: "db", Addr: "127.0.0.1:5432"}, Interval: time.Hour})
+
+	browser, err := config.Browse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = browser.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	actual, err := browser.Resolve(ctx, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(service, actual) {
+		t.Errorf("expected %v; actual %v", service, actual)
+	}
+
+	if _, err := browser.Resolve(ctx, "db"); err != nil {
+		t.Fatal(err)
+	}
+
+	if services := browser.Services(); len(services) != 2 {
+		t.Errorf("expected 2 services; actual %v", services)
+	}
+}
+
+func TestBye(t *testing.T) {
+	config := loopbackConfig(t)
+
+	browser, err := config.Browse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = browser.Close() }()
+
+	stop := announce(t, &Announcer{Config: config, Service: Service{Name: "api", Addr: "127.0.0.1:8080"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := browser.Resolve(ctx, "api"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The service disappears right away, long before the TTL expires
+	stop()
+	deadline := time.Now().Add(DefaultTTL / 2)
+	for len(browser.Lookup("api")) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("service is still there after bye")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	config := loopbackConfig(t)
+
+	browser, err := config.Browse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = browser.Close() }()
+
+	// Announced once, valid for a moment
+	announce(t, &Announcer{
+		Config:   config,
+		Service:  Service{Name: "api", Addr: "127.0.0.1:8080"},
+		Interval: time.Hour,
+		TTL:      100 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := browser.Resolve(ctx, "api"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if services := browser.Lookup("api"); len(services) != 0 {
+		t.Errorf("expected the service to expire; actual %v", services)
+	}
+}
+
+func TestResolveTimeout(t *testing.T) {
+	browser, err := loopbackConfig(t).Browse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = browser.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := browser.Resolve(ctx, "nobody"); err != context.DeadlineExceeded {
+		t.Errorf("expected %v; actual %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,69 @@
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Message types
+const (
+	// Service is available for TTL
+	typeAnnounce = "announce"
+	// Service is going away
+	typeBye = "bye"
+	// Browser asks services (all, or with the given name) to announce now
+	typeQuery = "query"
+)
+
+var errInvalidMessage = errors.New("discovery: invalid message")
+
+// struct message - a single datagram, encoded as JSON
+type message struct {
+	Type    string  `json:"type"`
+	Service Service `json:"service"`
+	// Validity of the announcement, in milliseconds
+	TTL int64 `json:"ttl,omitempty"`
+}
+
+// Func ttl
+func (m message) ttl() time.Duration { return time.Duration(m.TTL) * time.Millisecond }
+
+// func MarshalBinary
+func (m message) MarshalBinary() ([]byte, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) > maxMessageSize {
+		return nil, errors.New("discovery: message too large")
+	}
+
+	return b, nil
+}
+
+// func UnmarshalBinary
+func (m *message) UnmarshalBinary(b []byte) error {
+	var v message
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch v.Type {
+	case typeAnnounce:
+		if v.Service.Name == "" || v.Service.Addr == "" || v.TTL <= 0 {
+			return errInvalidMessage
+		}
+	case typeBye:
+		if v.Service.Name == "" || v.Service.Addr == "" {
+			return errInvalidMessage
+		}
+	case typeQuery:
+	default:
+		return errInvalidMessage
+	}
+
+	*m = v
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package discovery
+
+import "syscall"
+
+// Func reuseAddr - no-op: only one broadcast group member per host
+func reuseAddr(_, _ string, _ syscall.RawConn) error { return nil }
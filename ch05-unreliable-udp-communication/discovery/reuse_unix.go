@@ -0,0 +1,22 @@
+//go:build unix
+
+package discovery
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Func reuseAddr - let every member of a broadcast group bind the same port
+func reuseAddr(_, _ string, c syscall.RawConn) error {
+	var err error
+	cErr := c.Control(func(fd uintptr) {
+		err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+	})
+	if cErr != nil {
+		return cErr
+	}
+
+	return err
+}
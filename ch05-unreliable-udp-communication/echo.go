@@ -2,43 +2,28 @@ package ch5
 
 import (
 	"context"
-	"fmt"
+	"learn-network-programming/ch07-unix-domain-sockets/echo"
 	"net"
 )
 
 func echoServerUDP(ctx context.Context, addr string) (net.Addr, error) {
-	// Create UDP socket on an address
-	s, err := net.ListenPacket("udp", addr)
+	// Create UDP echo server on an address
+	s := &echo.Server{Network: "udp", Address: addr}
+	err := s.Listen()
 	if err != nil {
-		return nil, fmt.Errorf("binding to udp %s: %w", addr, err)
+		return nil, err
 	}
 
-	// Separate goroutine for echoing
+	// Separate goroutine to wait for context cancel and stop replying
 	go func() {
-		// Separate goroutine to wait for context cancel and stop replying
-		go func() {
-			<-ctx.Done()
-			_ = s.Close()
-		}()
-
-		// Prepare buffer
-		buf := make([]byte, 1024)
-
-		// Loop endlessly until error
-		for {
-			// Read message (from any address)
-			n, clientAddr, err := s.ReadFrom(buf)
-			if err != nil {
-				return
-			}
+		<-ctx.Done()
+		_ = s.Close()
+	}()
 
-			// Write same message back
-			_, err = s.WriteTo(buf[:n], clientAddr)
-			if err != nil {
-				return
-			}
-		}
+	// Separate goroutine for echoing
+	go func() {
+		_ = s.Serve()
 	}()
 
-	return s.LocalAddr(), nil
+	return s.Addr(), nil
 }
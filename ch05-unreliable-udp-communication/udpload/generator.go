@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"os"
+	"time"
+)
+
+// Struct generator - sends datagrams at a fixed rate and collects echoed replies
+type generator struct {
+	// Datagrams per second
+	rate int
+	// Size of a datagram, at least headerSize
+	size int
+	// Stop after sending that many datagrams (<= 0 means no limit)
+	count int
+	// Stop after sending for that long (<= 0 means no limit)
+	duration time.Duration
+	// Time to wait for late replies after the last datagram is sent
+	wait time.Duration
+}
+
+// Func run - load the echo server on the other end of conn until the count or
+// duration limit is reached or the context is done, and return the statistics
+// (conn should be connected, so that only the server's replies are read)
+func (g generator) run(ctx context.Context, conn net.Conn) *stats {
+	s := newStats(conn.RemoteAddr().String())
+	run := rand.Uint64()
+	start := time.Now()
+
+	// Receive in the background until the read deadline
+	// set after sending is finished
+	received := make(chan struct{})
+	go func() {
+		defer close(received)
+		g.receive(conn, run, start, s)
+	}()
+
+	s.sent = g.send(ctx, conn, run, start)
+	s.duration = time.Since(start)
+
+	_ = conn.SetReadDeadline(time.Now().Add(g.wait))
+	<-received
+
+	return s
+}
+
+// Func send - paced sending loop, returns the number of datagrams sent
+func (g generator) send(ctx context.Context, conn net.Conn, run uint64, start time.Time) int {
+	period := time.Second / time.Duration(g.rate)
+	buf := make([]byte, g.size)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	sent := 0
+	for g.count <= 0 || sent < g.count {
+		// Send on schedule: if we're behind (e.g., the sleep was too long),
+		// the next datagrams go out right away to keep the average rate
+		next := start.Add(time.Duration(sent) * period)
+		if g.duration > 0 && next.Sub(start) >= g.duration {
+			break
+		}
+
+		if d := time.Until(next); d > 0 {
+			timer.Reset(d)
+			select {
+			case <-ctx.Done():
+				return sent
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			return sent
+		}
+
+		packet{run: run, seq: uint64(sent), sent: time.Since(start)}.marshal(buf)
+		// Failed writes (e.g., ICMP port unreachable reported on a connected
+		// socket) are counted as sent, so they show up as loss
+		_, _ = conn.Write(buf)
+		sent++
+	}
+
+	return sent
+}
+
+// Func receive - account for replies until the read deadline or a fatal error
+func (g generator) receive(conn net.Conn, run uint64, start time.Time, s *stats) {
+	// Replies are echoes of the requests
+	buf := make([]byte, g.size)
+
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			// E.g., connection refused, the server might come up later
+			continue
+		}
+
+		var d packet
+		if err := d.unmarshal(buf[:n], run); err != nil {
+			continue
+		}
+
+		s.add(d.seq, time.Since(start)-d.sent)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"learn-network-programming/ch07-unix-domain-sockets/echo"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	s := newStats("target")
+	s.sent = 6
+
+	// seq 3 overtakes 1 and 2, seq 2 is duplicated, seq 5 is lost
+	for i, seq := range []uint64{0, 3, 1, 2, 2, 4} {
+		s.add(seq, time.Duration(i+1)*time.Millisecond)
+	}
+
+	if s.received != 5 || s.duplicates != 1 || s.reordered != 2 {
+		t.Errorf(
+			"expected 5 received, 1 duplicate, 2 reordered; actual %d, %d, %d",
+			s.received, s.duplicates, s.reordered,
+		)
+	}
+
+	if loss := s.loss(); loss < 16.6 || loss > 16.7 {
+		t.Errorf("expected 16.67%% loss; actual %.2f%%", loss)
+	}
+
+	for p, expected := range map[float64]time.Duration{
+		0:   time.Millisecond,
+		50:  3 * time.Millisecond,
+		100: 6 * time.Millisecond,
+	} {
+		if actual := s.percentile(p); actual != expected {
+			t.Errorf("p%v: expected %s; actual %s", p, expected, actual)
+		}
+	}
+
+	// Transit time grows by 1ms with every distinct reply
+	if s.jitter <= 0 || s.jitter >= float64(time.Millisecond) {
+		t.Errorf("expected jitter within (0, 1ms); actual %s", time.Duration(s.jitter))
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	server := &echo.Server{Network: "udp", Address: "127.0.0.1:"}
+	if err := server.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve() }()
+	defer func() { _ = server.Close() }()
+
+	conn, err := net.Dial("udp", server.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	start := time.Now()
+	s := generator{rate: 1000, size: 128, count: 200, wait: 500 * time.Millisecond}.
+		run(context.Background(), conn)
+
+	// 200 datagrams at 1000/s take about 200ms
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Errorf("sending wasn't paced: took %s", elapsed)
+	}
+
+	if s.sent != 200 {
+		t.Errorf("expected 200 sent; actual %d", s.sent)
+	}
+
+	// Loopback may drop under load, but not everything
+	if s.received == 0 || s.duplicates != 0 {
+		t.Errorf("expected replies without duplicates; actual %d received, %d duplicates", s.received, s.duplicates)
+	}
+
+	if s.percentile(100) <= 0 {
+		t.Error("expected positive round-trip times")
+	}
+}
+
+func TestGeneratorCancel(t *testing.T) {
+	conn, err := net.Dial("udp", "127.0.0.1:9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// No limits: only the context stops sending
+	s := generator{rate: 100, size: headerSize, wait: 10 * time.Millisecond}.run(ctx, conn)
+
+	if s.sent == 0 || s.sent > 20 {
+		t.Errorf("expected about 10 sent; actual %d", s.sent)
+	}
+
+	if s.received != 0 {
+		t.Errorf("expected no replies; actual %d", s.received)
+	}
+}
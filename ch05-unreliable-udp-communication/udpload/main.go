@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"learn-network-programming/ch06-ensuring-udp-reliability/datagram"
+	"learn-network-programming/ch07-unix-domain-sockets/echo"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Global variables - addresses of CLI parameters
+var (
+	// Load shape
+	rate = flag.Int("r", 1000, "datagrams per second")
+	size = flag.Int("s", 64, fmt.Sprintf("datagram size in bytes (at least %d)", headerSize))
+	// Limits: whichever comes first
+	count    = flag.Int("n", 0, "number of datagrams: <= 0 means no limit")
+	duration = flag.Duration("d", 10*time.Second, "sending duration: <= 0 means no limit")
+	// Time to wait for late replies
+	wait = flag.Duration("w", time.Second, "time to wait for replies after sending")
+	// Output format
+	jsonOutput = flag.Bool("json", false, "print the summary as JSON")
+	// Run an echo server instead of the generator
+	serve = flag.String("serve", "", "run a UDP echo server on the address instead")
+	// Probe the path MTU to the echo server instead of loading it
+	pmtu = flag.Bool("pmtu", false, "probe the path MTU to the target with DF-bit datagrams (Linux only)")
+)
+
+// Init function - called before main
+func init() {
+	// Initialize usage function (print description and default values)
+	flag.Usage = func() {
+		fmt.Printf(
+			"Usage:\n\t%[1]s [options] host:port\n\t%[1]s -pmtu host:port\n\t%[1]s -serve host:port\nOptions:\n",
+			os.Args[0],
+		)
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	// Parse CLI arguments
+	flag.Parse()
+
+	// Stop on Ctrl+C (or termination), the summary is printed anyway
+	ctx, stop := signal.NotifyContext(
+		context.Background(),
+		os.Interrupt,
+		syscall.SIGTERM,
+	)
+	defer stop()
+
+	if *serve != "" {
+		if err := runServer(ctx, *serve); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// If arguments are invalid: print warning, show usage, and exit
+	if flag.NArg() != 1 {
+		fmt.Printf("host:port is required\n\n")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *pmtu {
+		if err := probeMTU(ctx, flag.Arg(0)); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *rate <= 0 || *size < headerSize {
+		fmt.Printf("rate must be positive and size at least %d\n\n", headerSize)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *count <= 0 && *duration <= 0 {
+		fmt.Println("Press CTRL+C to exit.")
+	}
+
+	// Connected socket: replies from other addresses are filtered out
+	conn, err := net.Dial("udp", flag.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if !*jsonOutput {
+		fmt.Printf("UDP LOAD %s: %d datagrams/s, %d bytes\n", conn.RemoteAddr(), *rate, *size)
+	}
+
+	s := generator{
+		rate:     *rate,
+		size:     *size,
+		count:    *count,
+		duration: *duration,
+		wait:     *wait,
+	}.run(ctx, conn)
+
+	if *jsonOutput {
+		b, err := json.Marshal(s.toJSON())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(string(b))
+	} else {
+		fmt.Printf("\n%s", s)
+	}
+
+	// Exit with an error if nothing came back
+	if s.received == 0 {
+		os.Exit(1)
+	}
+}
+
+// Func probeMTU - find and print the largest unfragmented datagram to the echo server
+func probeMTU(ctx context.Context, address string) error {
+	raddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	payload, err := datagram.Prober{}.Probe(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"%s: path MTU %d bytes (%d bytes of UDP payload)\n",
+		raddr, payload+datagram.Overhead(raddr), payload,
+	)
+
+	return nil
+}
+
+// Func runServer - echo datagrams until the context is done
+func runServer(ctx context.Context, address string) error {
+	s := &echo.Server{
+		Network:    "udp",
+		Address:    address,
+		BufferSize: datagram.MaxUDPSize,
+		OnTruncated: func(from net.Addr) {
+			fmt.Printf("%s: datagram truncated, dropped\n", from)
+		},
+	}
+	if err := s.Listen(); err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = s.Close()
+	}()
+
+	fmt.Println("ECHO", s.Addr())
+
+	err := s.Serve()
+	if err == echo.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
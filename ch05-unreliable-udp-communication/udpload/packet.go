@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// Packet layout (the rest up to the packet size is padding):
+//
+//	| run id (8) | seq (8) | send time since the run start, ns (8) |
+const headerSize = 8 + 8 + 8
+
+var errForeignDatagram = errors.New("foreign datagram")
+
+// Struct packet - a sequenced, timestamped probe
+type packet struct {
+	// Random id of the run, to ignore replies to other runs
+	run uint64
+	seq uint64
+	// Send time relative to the run start (monotonic)
+	sent time.Duration
+}
+
+// Func marshal - encode the packet into b, which is the whole datagram
+func (d packet) marshal(b []byte) {
+	binary.BigEndian.PutUint64(b[0:8], d.run)
+	binary.BigEndian.PutUint64(b[8:16], d.seq)
+	binary.BigEndian.PutUint64(b[16:24], uint64(d.sent))
+}
+
+// Func unmarshal - decode a reply, rejecting replies to other runs
+func (d *packet) unmarshal(b []byte, run uint64) error {
+	if len(b) < headerSize || binary.BigEndian.Uint64(b[0:8]) != run {
+		return errForeignDatagram
+	}
+
+	d.run = run
+	d.seq = binary.BigEndian.Uint64(b[8:16])
+	d.sent = time.Duration(binary.BigEndian.Uint64(b[16:24]))
+
+	return nil
+}
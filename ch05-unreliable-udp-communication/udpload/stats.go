@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Struct stats - accumulated results of a run
+type stats struct {
+	// Target address
+	target string
+	// Number of datagrams sent
+	sent int
+	// Number of distinct replies, and replies received more than once
+	received   int
+	duplicates int
+	// Replies that arrived after a reply with a greater sequence number
+	reordered int
+	// Greatest sequence number received so far
+	maxSeq uint64
+	// Sequence numbers received so far
+	seen map[uint64]struct{}
+	// Round-trip times of distinct replies
+	rtts []time.Duration
+	// Interarrival jitter (RFC 3550, section 6.4.1) and the previous transit time
+	jitter  float64
+	lastRTT time.Duration
+	hasLast bool
+	// Time spent sending
+	duration time.Duration
+}
+
+// Func newStats
+func newStats(target string) *stats {
+	return &stats{target: target, seen: make(map[uint64]struct{})}
+}
+
+// Func add - account for a reply
+func (s *stats) add(seq uint64, rtt time.Duration) {
+	if _, ok := s.seen[seq]; ok {
+		s.duplicates++
+		return
+	}
+	s.seen[seq] = struct{}{}
+	s.received++
+
+	if s.received > 1 && seq < s.maxSeq {
+		s.reordered++
+	}
+	s.maxSeq = max(s.maxSeq, seq)
+
+	s.rtts = append(s.rtts, rtt)
+
+	// Difference of transit times of consecutive replies: RTT works
+	// as the transit time, since the clocks of both ends are the same
+	if s.hasLast {
+		d := math.Abs(float64(rtt - s.lastRTT))
+		s.jitter += (d - s.jitter) / 16
+	}
+	s.lastRTT = rtt
+	s.hasLast = true
+}
+
+// Func loss - percentage of datagrams without a reply
+func (s *stats) loss() float64 {
+	if s.sent == 0 {
+		return 0
+	}
+
+	return 100 * float64(s.sent-s.received) / float64(s.sent)
+}
+
+// Func percentile - nearest-rank percentile of round-trip times
+func (s *stats) percentile(p float64) time.Duration {
+	if len(s.rtts) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(s.rtts)
+	slices.Sort(sorted)
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = min(max(rank, 1), len(sorted))
+
+	return sorted[rank-1]
+}
+
+// Func String - human-readable summary
+func (s *stats) String() string {
+	summary := fmt.Sprintf(
+		"--- %s udp load statistics ---\n"+
+			"%d datagrams sent in %s, %d received, %.2f%% loss, %d duplicates, %d reordered\n",
+		s.target, s.sent, s.duration.Round(time.Millisecond),
+		s.received, s.loss(), s.duplicates, s.reordered,
+	)
+
+	// Round-trip times only make sense if anything was received
+	if s.received > 0 {
+		summary += fmt.Sprintf(
+			"rtt min/p50/p90/p99/max = %s/%s/%s/%s/%s, jitter %s\n",
+			s.percentile(0), s.percentile(50), s.percentile(90),
+			s.percentile(99), s.percentile(100), time.Duration(s.jitter),
+		)
+	}
+
+	return summary
+}
+
+// Struct statsJSON - machine-readable summary
+type statsJSON struct {
+	Target      string  `json:"target"`
+	Sent        int     `json:"sent"`
+	Received    int     `json:"received"`
+	LossPercent float64 `json:"loss_percent"`
+	Duplicates  int     `json:"duplicates"`
+	Reordered   int     `json:"reordered"`
+	DurationMs  float64 `json:"duration_ms"`
+	MinMs       float64 `json:"min_ms"`
+	P50Ms       float64 `json:"p50_ms"`
+	P90Ms       float64 `json:"p90_ms"`
+	P99Ms       float64 `json:"p99_ms"`
+	MaxMs       float64 `json:"max_ms"`
+	JitterMs    float64 `json:"jitter_ms"`
+}
+
+// Func toJSON - convert stats to their JSON representation
+func (s *stats) toJSON() statsJSON {
+	return statsJSON{
+		Target:      s.target,
+		Sent:        s.sent,
+		Received:    s.received,
+		LossPercent: s.loss(),
+		Duplicates:  s.duplicates,
+		Reordered:   s.reordered,
+		DurationMs:  milliseconds(s.duration),
+		MinMs:       milliseconds(s.percentile(0)),
+		P50Ms:       milliseconds(s.percentile(50)),
+		P90Ms:       milliseconds(s.percentile(90)),
+		P99Ms:       milliseconds(s.percentile(99)),
+		MaxMs:       milliseconds(s.percentile(100)),
+		JitterMs:    milliseconds(time.Duration(s.jitter)),
+	}
+}
+
+// Func milliseconds - duration as fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,65 @@
+package datagram
+
+import (
+	"errors"
+	"net"
+)
+
+// MaxUDPSize is the largest UDP payload over IPv4
+// (65535 minus the IPv4 and UDP headers)
+const MaxUDPSize = 65507
+
+// ErrTruncated is returned (along with the truncated data)
+// when a datagram didn't fit into the read buffer
+var ErrTruncated = errors.New("datagram truncated")
+
+// Func ReadFrom - read a datagram like pc.ReadFrom, but report truncation:
+// if the datagram was larger than b, the first len(b) bytes are returned
+// with ErrTruncated.
+//
+// Truncation is detected from the MSG_TRUNC flag of UDP and unixgram sockets
+// on unix systems; other connections can't tell a datagram that exactly
+// fits into b from a larger one and never report it.
+func ReadFrom(pc net.PacketConn, b []byte) (int, net.Addr, error) {
+	var (
+		n     int
+		flags int
+		addr  net.Addr
+		err   error
+	)
+
+	switch conn := pc.(type) {
+	case *net.UDPConn:
+		var udpAddr *net.UDPAddr
+		n, _, flags, udpAddr, err = conn.ReadMsgUDP(b, nil)
+		if udpAddr != nil {
+			addr = udpAddr
+		}
+	case *net.UnixConn:
+		var unixAddr *net.UnixAddr
+		n, _, flags, unixAddr, err = conn.ReadMsgUnix(b, nil)
+		if unixAddr != nil {
+			addr = unixAddr
+		}
+	default:
+		return pc.ReadFrom(b)
+	}
+
+	if err == nil && msgTrunc != 0 && flags&msgTrunc != 0 {
+		err = ErrTruncated
+	}
+
+	return n, addr, err
+}
+
+// Func Overhead - size of the IP and UDP headers in front of the payload,
+// path MTU minus the overhead is the largest payload that isn't fragmented
+func Overhead(addr *net.UDPAddr) int {
+	const udpHeader = 8
+
+	if addr != nil && addr.IP.To4() == nil && len(addr.IP) == net.IPv6len {
+		return 40 + udpHeader
+	}
+
+	return 20 + udpHeader
+}
@@ -0,0 +1,103 @@
+package datagram
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+// testPair - a receiving packet connection and a client connected to it
+func testPair(t *testing.T, network string) (net.PacketConn, net.Conn) {
+	t.Helper()
+
+	switch network {
+	case "udp":
+		server, err := net.ListenPacket("udp", "127.0.0.1:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = server.Close() })
+
+		client, err := net.Dial("udp", server.LocalAddr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = client.Close() })
+
+		return server, client
+	case "unixgram":
+		dir := t.TempDir()
+
+		server, err := net.ListenPacket("unixgram", filepath.Join(dir, "server.sock"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = server.Close() })
+
+		local := &net.UnixAddr{Name: filepath.Join(dir, "client.sock"), Net: "unixgram"}
+		client, err := net.DialUnix("unixgram", local, server.LocalAddr().(*net.UnixAddr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = client.Close() })
+
+		return server, client
+	}
+
+	t.Fatalf("unexpected network %q", network)
+	return nil, nil
+}
+
+func TestReadFromTruncated(t *testing.T) {
+	for _, network := range []string{"udp", "unixgram"} {
+		t.Run(network, func(t *testing.T) {
+			server, client := testPair(t, network)
+
+			// exactly fits, then doesn't fit
+			for _, msg := range [][]byte{[]byte("12345678"), []byte("123456789")} {
+				if _, err := client.Write(msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			buf := make([]byte, 8)
+
+			n, addr, err := ReadFrom(server, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf[:n], []byte("12345678")) {
+				t.Errorf("expected %q; actual %q", "12345678", buf[:n])
+			}
+			if addr.String() != client.LocalAddr().String() {
+				t.Errorf("expected sender %q; actual %q", client.LocalAddr(), addr)
+			}
+
+			n, addr, err = ReadFrom(server, buf)
+			if !errors.Is(err, ErrTruncated) {
+				t.Fatalf("expected ErrTruncated; actual %v", err)
+			}
+			if n != len(buf) || addr == nil {
+				t.Errorf("expected %d bytes with the sender; actual %d bytes from %v", len(buf), n, addr)
+			}
+		})
+	}
+}
+
+func TestOverhead(t *testing.T) {
+	for addr, expected := range map[string]int{
+		"127.0.0.1:53": 28,
+		"[::1]:53":     48,
+	} {
+		udpAddr, err := net.ResolveUDPAddr("udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := Overhead(udpAddr); actual != expected {
+			t.Errorf("%s: expected %d; actual %d", addr, expected, actual)
+		}
+	}
+}
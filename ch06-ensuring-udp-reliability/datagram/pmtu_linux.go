@@ -0,0 +1,60 @@
+package datagram
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// Func SetDontFragment - send datagrams with the DF bit set, so that the ones
+// larger than the path MTU are dropped instead of fragmented (IPv6 never
+// fragments in transit anyway). The kernel's path MTU estimate is ignored,
+// so probes larger than it are still sent.
+func SetDontFragment(conn *net.UDPConn) error {
+	return control(conn, func(fd int) error {
+		if isIPv6(fd) {
+			return unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_MTU_DISCOVER, unix.IPV6_PMTUDISC_PROBE)
+		}
+		return unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_PROBE)
+	})
+}
+
+// Func PathMTU - the kernel's current path MTU estimate for a connected socket
+func PathMTU(conn *net.UDPConn) (int, error) {
+	var mtu int
+	err := control(conn, func(fd int) error {
+		var err error
+		if isIPv6(fd) {
+			mtu, err = unix.GetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_MTU)
+		} else {
+			mtu, err = unix.GetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_MTU)
+		}
+		return err
+	})
+
+	return mtu, err
+}
+
+// Func control - run f on the socket's descriptor
+func control(conn *net.UDPConn, f func(fd int) error) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var fErr error
+	err = raw.Control(func(fd uintptr) {
+		fErr = f(int(fd))
+	})
+	if err != nil {
+		return err
+	}
+
+	return fErr
+}
+
+// Func isIPv6 - whether the socket is an IPv6 one
+func isIPv6(fd int) bool {
+	domain, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_DOMAIN)
+	return err == nil && domain == unix.AF_INET6
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package datagram
+
+import (
+	"errors"
+	"net"
+)
+
+// Func SetDontFragment - not supported on this platform
+func SetDontFragment(_ *net.UDPConn) error { return errors.ErrUnsupported }
+
+// Func PathMTU - not supported on this platform
+func PathMTU(_ *net.UDPConn) (int, error) { return 0, errors.ErrUnsupported }
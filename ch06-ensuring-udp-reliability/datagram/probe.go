@@ -0,0 +1,162 @@
+package datagram
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Defaults for the zero Prober
+const (
+	// Every IPv4 host accepts 576-byte datagrams, minus the headers
+	DefaultMinProbe = 576 - 28
+	// Time to wait for the echo of a probe
+	DefaultProbeTimeout = 500 * time.Millisecond
+	// Attempts per probe size, a lost probe doesn't mean it's too large
+	DefaultProbeRetries = 3
+)
+
+// Size of the probe header: random token and the probe size
+const probeHeaderSize = 8 + 4
+
+// Prober finds the largest UDP payload that reaches an echo server
+// unfragmented, by sending DF-bit probes of different sizes (Linux only)
+type Prober struct {
+	// Smallest and largest payload sizes to try; the largest defaults
+	// to the kernel's path MTU estimate (the interface MTU if nothing
+	// is known about the path) minus the headers
+	Min int
+	Max int
+	// Time to wait for an echo, DefaultProbeTimeout if zero
+	Timeout time.Duration
+	// Attempts per size, DefaultProbeRetries if zero
+	Retries int
+}
+
+// Func Probe - binary search for the largest payload echoed back by the peer
+// of the connected socket; path MTU is the result plus Overhead
+func (p Prober) Probe(ctx context.Context, conn *net.UDPConn) (int, error) {
+	p = p.withDefaults(conn)
+	if p.Max < p.Min {
+		return 0, fmt.Errorf("probe sizes: max %d < min %d", p.Max, p.Min)
+	}
+
+	if err := SetDontFragment(conn); err != nil {
+		return 0, fmt.Errorf("set DF bit: %w", err)
+	}
+
+	// The smallest size must pass, or the peer isn't an echo server
+	ok, err := p.try(ctx, conn, p.Min)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("no echo for %d bytes", p.Min)
+	}
+
+	// Invariant: low passes, everything above high fails
+	low, high := p.Min, p.Max
+	for low < high {
+		mid := low + (high-low+1)/2
+
+		ok, err := p.try(ctx, conn, mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if ok {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+
+	return low, nil
+}
+
+// Func withDefaults - copy of the prober with unset fields defaulted
+func (p Prober) withDefaults(conn *net.UDPConn) Prober {
+	if p.Min <= 0 {
+		p.Min = DefaultMinProbe
+	}
+	p.Min = max(p.Min, probeHeaderSize)
+
+	if p.Max <= 0 {
+		p.Max = MaxUDPSize
+		if mtu, err := PathMTU(conn); err == nil {
+			remote, _ := conn.RemoteAddr().(*net.UDPAddr)
+			p.Max = min(p.Max, mtu-Overhead(remote))
+		}
+	}
+
+	if p.Timeout <= 0 {
+		p.Timeout = DefaultProbeTimeout
+	}
+
+	if p.Retries <= 0 {
+		p.Retries = DefaultProbeRetries
+	}
+
+	return p
+}
+
+// Func try - whether a probe of the size comes back
+func (p Prober) try(ctx context.Context, conn *net.UDPConn, size int) (bool, error) {
+	probe := make([]byte, size)
+	if _, err := rand.Read(probe[:8]); err != nil {
+		return false, err
+	}
+	binary.BigEndian.PutUint32(probe[8:probeHeaderSize], uint32(size))
+
+	buf := make([]byte, size+1)
+	for attempt := 0; attempt < p.Retries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		if _, err := conn.Write(probe); err != nil {
+			// Larger than the interface MTU: rejected right away
+			if errors.Is(err, syscall.EMSGSIZE) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		deadline := time.Now().Add(p.Timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		_ = conn.SetReadDeadline(deadline)
+
+		for {
+			n, err := conn.Read(buf)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			if err != nil {
+				// E.g., ICMP "fragmentation needed" reported on the socket
+				if errors.Is(err, syscall.EMSGSIZE) {
+					return false, nil
+				}
+				return false, err
+			}
+
+			// Skip late echoes of earlier probes
+			if n == size && bytes.Equal(buf[:probeHeaderSize], probe[:probeHeaderSize]) {
+				_ = conn.SetReadDeadline(time.Time{})
+				return true, nil
+			}
+		}
+	}
+
+	_ = conn.SetReadDeadline(time.Time{})
+
+	return false, nil
+}
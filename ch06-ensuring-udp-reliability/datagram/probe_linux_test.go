@@ -0,0 +1,123 @@
+package datagram
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// testEchoServer - echo datagrams up to maxSize bytes, drop larger ones
+func testEchoServer(t *testing.T, maxSize int) net.Addr {
+	t.Helper()
+
+	server, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+
+	go func() {
+		buf := make([]byte, maxSize)
+		for {
+			n, addr, err := ReadFrom(server, buf)
+			if errors.Is(err, ErrTruncated) {
+				continue
+			}
+			if err != nil {
+				return
+			}
+
+			_, _ = server.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	return server.LocalAddr()
+}
+
+// testDialUDP - client connected to the address
+func testDialUDP(t *testing.T, addr net.Addr) *net.UDPConn {
+	t.Helper()
+
+	conn, err := net.DialUDP("udp", nil, addr.(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestPathMTU(t *testing.T) {
+	conn := testDialUDP(t, testEchoServer(t, 16))
+
+	if err := SetDontFragment(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	mtu, err := PathMTU(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Loopback MTU is 64K nowadays, but never less than the Ethernet one
+	if mtu < 1500 {
+		t.Errorf("expected loopback MTU of at least 1500; actual %d", mtu)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	// The server stands for a path that drops datagrams larger than 1400 bytes
+	conn := testDialUDP(t, testEchoServer(t, 1400))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	size, err := Prober{Max: 9000, Timeout: 50 * time.Millisecond, Retries: 2}.Probe(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != 1400 {
+		t.Errorf("expected 1400; actual %d", size)
+	}
+}
+
+func TestProbeInterfaceMTU(t *testing.T) {
+	conn := testDialUDP(t, testEchoServer(t, MaxUDPSize+1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Nothing is lost on loopback: the limit is the interface MTU
+	size, err := Prober{Timeout: 200 * time.Millisecond}.Probe(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mtu, err := PathMTU(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := min(mtu-Overhead(nil), MaxUDPSize); size != expected {
+		t.Errorf("expected %d; actual %d", expected, size)
+	}
+}
+
+func TestProbeNoEcho(t *testing.T) {
+	// Nobody answers, not even with ICMP: a bound but silent socket
+	silent, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = silent.Close() }()
+
+	conn := testDialUDP(t, silent.LocalAddr())
+
+	_, err = Prober{Timeout: 20 * time.Millisecond, Retries: 1}.Probe(context.Background(), conn)
+	if err == nil {
+		t.Fatal("expected error without echoes")
+	}
+}
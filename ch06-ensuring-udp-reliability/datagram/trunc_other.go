@@ -0,0 +1,6 @@
+//go:build !unix
+
+package datagram
+
+// No truncation flag on this platform
+const msgTrunc = 0
@@ -0,0 +1,8 @@
+//go:build unix
+
+package datagram
+
+import "syscall"
+
+// Flag of recvmsg(2): the datagram was larger than the buffer
+const msgTrunc = syscall.MSG_TRUNC
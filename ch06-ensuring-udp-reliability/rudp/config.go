@@ -0,0 +1,95 @@
+package rudp
+
+import (
+	"net"
+	"time"
+)
+
+// Defaults for the zero Config
+const (
+	DefaultMaxPayload = 1024
+	DefaultWindow     = 64
+	DefaultRTO        = 200 * time.Millisecond
+	DefaultMaxRetries = 10
+)
+
+// Config tunes reliable connections, the zero value uses the defaults
+type Config struct {
+	// Maximum payload of a single datagram (should fit into the path MTU)
+	MaxPayload int
+	// Maximum number of unacknowledged datagrams in flight
+	Window int
+	// Retransmission timeout
+	RTO time.Duration
+	// Retransmissions of a datagram before the peer is considered unreachable
+	MaxRetries int
+	// Maximum in-order data waiting for Read in bytes (Window * MaxPayload
+	// if not set): once exceeded, no more datagrams are acknowledged,
+	// so the sender's window fills up and its writes block
+	ReadBuffer int
+}
+
+// Func withDefaults - copy of the config with unset fields defaulted
+func (c Config) withDefaults() Config {
+	if c.MaxPayload <= 0 {
+		c.MaxPayload = DefaultMaxPayload
+	}
+
+	if c.Window <= 0 {
+		c.Window = DefaultWindow
+	}
+
+	if c.RTO <= 0 {
+		c.RTO = DefaultRTO
+	}
+
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = DefaultMaxRetries
+	}
+
+	if c.ReadBuffer <= 0 {
+		c.ReadBuffer = c.Window * c.MaxPayload
+	}
+
+	return c
+}
+
+// Func Dial - connect to a reliable-UDP listener at the address
+func (c Config) Dial(network, address string) (*Conn, error) {
+	remote, err := net.ResolveUDPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bind to a random port on all interfaces
+	pc, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := c.NewConn(pc, remote)
+	// The connection owns the socket
+	conn.onClose = func() { _ = pc.Close() }
+
+	return conn, nil
+}
+
+// Func Listen - accept reliable-UDP connections on the address
+func (c Config) Listen(network, address string) (*Listener, error) {
+	pc, err := net.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.NewListener(pc), nil
+}
+
+// Func Dial - Dial with the default config
+func Dial(network, address string) (*Conn, error) {
+	return Config{}.Dial(network, address)
+}
+
+// Func Listen - Listen with the default config
+func Listen(network, address string) (*Listener, error) {
+	return Config{}.Listen(network, address)
+}
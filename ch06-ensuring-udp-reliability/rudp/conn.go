@@ -0,0 +1,558 @@
+package rudp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"learn-network-programming/ch06-ensuring-udp-reliability/datagram"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrPeerUnreachable is returned when a datagram wasn't acknowledged
+// after all retransmissions
+var ErrPeerUnreachable = errors.New("rudp: peer unreachable")
+
+// ErrUnacknowledged is returned by Close when data was still in flight
+// after all retransmissions could have happened
+var ErrUnacknowledged = errors.New("rudp: data not acknowledged before close")
+
+// Conn is a reliable, ordered byte stream over datagrams:
+// every datagram carries a sequence number and is retransmitted
+// until acknowledged, the receiver suppresses duplicates and
+// reorders datagrams before delivering them.
+//
+// Conn implements net.Conn, so stream-oriented code runs over it unchanged.
+//
+// The dialing side opens the connection with a SYN, which takes the first
+// sequence number and is retransmitted like data, so the listener accepts
+// the connection even if the dialer never writes.
+type Conn struct {
+	config Config
+	pc     net.PacketConn
+	remote net.Addr
+	// Called once the connection is closed (e.g., to release the socket)
+	onClose func()
+
+	mu sync.Mutex
+
+	// Sending side: next sequence number and datagrams in flight
+	nextSeq uint32
+	unacked map[uint32]*outgoing
+
+	// Receiving side: next expected sequence number, datagrams received
+	// ahead of it, and in-order data not read yet
+	expected   uint32
+	outOfOrder map[uint32]packet
+	readBuf    bytes.Buffer
+	// Peer's FIN has been delivered in order
+	remoteClosed bool
+
+	// Local Close has been called / completed
+	closing bool
+	closed  bool
+	// Fatal error (e.g., ErrPeerUnreachable)
+	err error
+
+	readDeadline  time.Time
+	writeDeadline time.Time
+
+	// Notifications for blocked readers and writers
+	readable chan struct{}
+	writable chan struct{}
+	// Closed when the connection is closed
+	done chan struct{}
+	// Closed when readLoop returns (nil if the connection has none)
+	reading chan struct{}
+}
+
+// struct outgoing - datagram waiting for acknowledgement
+type outgoing struct {
+	data    []byte
+	sentAt  time.Time
+	retries int
+}
+
+// Func newConn - create a connection without its own read loop
+// (packets are fed by the caller through handle)
+func newConn(config Config, pc net.PacketConn, remote net.Addr) *Conn {
+	c := &Conn{
+		config:     config.withDefaults(),
+		pc:         pc,
+		remote:     remote,
+		unacked:    make(map[uint32]*outgoing),
+		outOfOrder: make(map[uint32]packet),
+		readable:   make(chan struct{}, 1),
+		writable:   make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+
+	go c.retransmitLoop()
+
+	return c
+}
+
+// Func NewConn - create a reliable connection to the remote address over pc,
+// reading from pc and ignoring datagrams from other addresses.
+//
+// Close stops reading from pc (interrupting ReadFrom with a read deadline),
+// but leaves it open.
+func (c Config) NewConn(pc net.PacketConn, remote net.Addr) *Conn {
+	conn := newConn(c, pc, remote)
+	conn.reading = make(chan struct{})
+	go conn.readLoop()
+
+	// Open the connection on the listener side
+	conn.mu.Lock()
+	_ = conn.sendLocked(typeSyn, nil)
+	conn.mu.Unlock()
+
+	return conn
+}
+
+// Func readLoop - read datagrams from the remote address and handle them
+func (c *Conn) readLoop() {
+	defer close(c.reading)
+
+	buf := newReadBuffer(c.config.MaxPayload)
+
+	for {
+		n, addr, err := readDatagram(c.pc, buf)
+		// Too large for the payload limit, the peer will fail to deliver it
+		if errors.Is(err, datagram.ErrTruncated) {
+			continue
+		}
+		if err != nil {
+			c.mu.Lock()
+			if !c.closed {
+				c.failLocked(err)
+			}
+			c.mu.Unlock()
+			return
+		}
+
+		if addr.String() != c.remote.String() {
+			continue
+		}
+
+		var p packet
+		if err := p.UnmarshalBinary(buf[:n]); err != nil {
+			continue
+		}
+
+		c.handle(p)
+	}
+}
+
+// Func handle - process an incoming packet
+func (c *Conn) handle(p packet) {
+	switch p.typ {
+	case typeAck:
+		c.handleAck(p)
+	case typeData, typeSyn, typeFin:
+		c.handleData(p)
+	}
+}
+
+// Func handleAck - forget acknowledged datagrams and wake up writers
+func (c *Conn) handleAck(p packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Cumulative part: everything before the next expected sequence number
+	for seq, o := range c.unacked {
+		if int32(seq-p.seq) < 0 {
+			delete(c.unacked, seq)
+			continue
+		}
+
+		// Any ACK proves the peer is reachable, it may just not accept
+		// more data yet (see Config.ReadBuffer), so retransmissions
+		// only count towards MaxRetries while nothing comes back
+		o.retries = 0
+	}
+
+	// Selective part: datagrams received out of order
+	for i := uint32(0); i < sackBits; i++ {
+		if p.sack&(1<<i) != 0 {
+			delete(c.unacked, p.seq+1+i)
+		}
+	}
+
+	notify(c.writable)
+}
+
+// Func handleData - buffer the datagram, deliver everything in order,
+// and acknowledge (duplicates are acknowledged again, but not delivered)
+func (c *Conn) handleData(p packet) {
+	c.mu.Lock()
+
+	// Store datagrams within the receive window
+	if p.seq-c.expected < uint32(c.config.Window) {
+		if _, ok := c.outOfOrder[p.seq]; !ok {
+			c.outOfOrder[p.seq] = p
+		}
+	}
+
+	delivered := c.deliverLocked()
+	ack := c.ackLocked()
+	c.mu.Unlock()
+
+	_, _ = c.pc.WriteTo(ack, c.remote)
+
+	if delivered {
+		notify(c.readable)
+	}
+}
+
+// Func deliverLocked - move consecutive datagrams to the read buffer
+// until it's full, must be called under lock
+func (c *Conn) deliverLocked() bool {
+	delivered := false
+	for {
+		next, ok := c.outOfOrder[c.expected]
+		if !ok {
+			break
+		}
+
+		// Flow control: keep the datagram unacknowledged until Read
+		// makes room for it (SYN and FIN take no room)
+		if next.typ == typeData && c.readBuf.Len() >= c.config.ReadBuffer {
+			break
+		}
+
+		delete(c.outOfOrder, c.expected)
+		c.expected++
+		delivered = true
+
+		switch next.typ {
+		case typeData:
+			c.readBuf.Write(next.payload)
+		case typeFin:
+			c.remoteClosed = true
+		}
+	}
+
+	return delivered
+}
+
+// Func ack - encoded ACK for the current receive state
+func (c *Conn) ack() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ackLocked()
+}
+
+// Func ackLocked - encoded ACK for the current receive state, must be called under lock
+func (c *Conn) ackLocked() []byte {
+	p := packet{typ: typeAck, seq: c.expected}
+
+	for i := uint32(0); i < sackBits; i++ {
+		if _, ok := c.outOfOrder[c.expected+1+i]; ok {
+			p.sack |= 1 << i
+		}
+	}
+
+	b, _ := p.MarshalBinary()
+	return b
+}
+
+// Func sendLocked - assign a sequence number, remember and send the datagram,
+// must be called under lock
+func (c *Conn) sendLocked(typ packetType, payload []byte) error {
+	p := packet{typ: typ, seq: c.nextSeq, payload: payload}
+	b, err := p.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	c.unacked[p.seq] = &outgoing{data: b, sentAt: time.Now()}
+	c.nextSeq++
+
+	// Lost datagrams are retransmitted, so write errors aren't fatal
+	_, _ = c.pc.WriteTo(b, c.remote)
+
+	return nil
+}
+
+// Func retransmitLoop - resend datagrams not acknowledged within RTO
+func (c *Conn) retransmitLoop() {
+	ticker := time.NewTicker(c.config.RTO / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			c.retransmit(now)
+		}
+	}
+}
+
+// Func retransmit - resend expired datagrams, fail if retries are exhausted
+func (c *Conn) retransmit(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+
+	for _, o := range c.unacked {
+		if now.Sub(o.sentAt) < c.config.RTO {
+			continue
+		}
+
+		if o.retries >= c.config.MaxRetries {
+			c.failLocked(ErrPeerUnreachable)
+			return
+		}
+
+		o.retries++
+		o.sentAt = now
+		_, _ = c.pc.WriteTo(o.data, c.remote)
+	}
+}
+
+// Func failLocked - record a fatal error and wake everybody up,
+// must be called under lock
+func (c *Conn) failLocked(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+
+	notify(c.readable)
+	notify(c.writable)
+}
+
+// Func Read - read in-order data, io.EOF after the peer closed the connection
+func (c *Conn) Read(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		switch {
+		case c.readBuf.Len() > 0:
+			n, _ := c.readBuf.Read(b)
+
+			// Deliver datagrams held back by flow control and let the
+			// peer know right away instead of waiting for retransmissions
+			var ack []byte
+			if c.deliverLocked() {
+				ack = c.ackLocked()
+			}
+			c.mu.Unlock()
+
+			if ack != nil {
+				_, _ = c.pc.WriteTo(ack, c.remote)
+			}
+			return n, nil
+		case c.remoteClosed:
+			c.mu.Unlock()
+			return 0, io.EOF
+		case c.closing:
+			c.mu.Unlock()
+			return 0, net.ErrClosed
+		case c.err != nil:
+			err := c.err
+			c.mu.Unlock()
+			return 0, err
+		}
+		deadline := c.readDeadline
+		c.mu.Unlock()
+
+		if err := c.wait(c.readable, deadline); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Func Write - split data into datagrams and send them, blocking while the window is full
+func (c *Conn) Write(b []byte) (int, error) {
+	written := 0
+
+	for len(b) > 0 {
+		c.mu.Lock()
+		switch {
+		case c.closing:
+			c.mu.Unlock()
+			return written, net.ErrClosed
+		case c.err != nil:
+			err := c.err
+			c.mu.Unlock()
+			return written, err
+		}
+
+		// Wait for acknowledgements if the window is full
+		if len(c.unacked) >= c.config.Window {
+			deadline := c.writeDeadline
+			c.mu.Unlock()
+
+			if err := c.wait(c.writable, deadline); err != nil {
+				return written, err
+			}
+			continue
+		}
+
+		chunk := b[:min(len(b), c.config.MaxPayload)]
+		err := c.sendLocked(typeData, chunk)
+		c.mu.Unlock()
+
+		if err != nil {
+			return written, err
+		}
+
+		written += len(chunk)
+		b = b[len(chunk):]
+	}
+
+	return written, nil
+}
+
+// Func wait - wait for a notification, closing, or the deadline
+func (c *Conn) wait(ch <-chan struct{}, deadline time.Time) error {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		d := time.Until(deadline)
+		if d <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ch:
+	case <-c.done:
+	case <-timeout:
+		return os.ErrDeadlineExceeded
+	}
+
+	return nil
+}
+
+// Func Close - send FIN and wait until all data (and FIN) is acknowledged
+// or the peer turns out to be unreachable (ErrPeerUnreachable or
+// ErrUnacknowledged is returned then)
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closing {
+		c.mu.Unlock()
+		return net.ErrClosed
+	}
+	c.closing = true
+
+	// Wake up blocked readers and writers
+	notify(c.readable)
+	notify(c.writable)
+
+	// If the peer has closed already, nobody may acknowledge our FIN,
+	// so it's sent on the best-effort basis
+	linger := c.err == nil && !c.remoteClosed
+	if c.err == nil {
+		_ = c.sendLocked(typeFin, nil)
+	}
+	c.mu.Unlock()
+
+	var err error
+	if linger {
+		err = c.drain()
+	}
+
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	close(c.done)
+
+	// Stop reading from a socket owned by the caller, which may keep using it
+	if c.reading != nil {
+		_ = c.pc.SetReadDeadline(time.Now())
+		<-c.reading
+		_ = c.pc.SetReadDeadline(time.Time{})
+	}
+
+	if c.onClose != nil {
+		c.onClose()
+	}
+
+	return err
+}
+
+// Func drain - wait until nothing is in flight, the connection fails,
+// or all retransmissions could have happened
+func (c *Conn) drain() error {
+	deadline := time.Now().Add(c.config.RTO * time.Duration(c.config.MaxRetries+1))
+
+	for {
+		c.mu.Lock()
+		unacked, err := len(c.unacked), c.err
+		c.mu.Unlock()
+
+		switch {
+		case unacked == 0:
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if err := c.wait(c.writable, deadline); err != nil {
+			return ErrUnacknowledged
+		}
+	}
+}
+
+// Func LocalAddr
+func (c *Conn) LocalAddr() net.Addr { return c.pc.LocalAddr() }
+
+// Func RemoteAddr
+func (c *Conn) RemoteAddr() net.Addr { return c.remote }
+
+// Func SetDeadline
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.writeDeadline = t
+	c.mu.Unlock()
+
+	// Let blocked calls pick up the new deadline
+	notify(c.readable)
+	notify(c.writable)
+
+	return nil
+}
+
+// Func SetReadDeadline
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+
+	notify(c.readable)
+	return nil
+}
+
+// Func SetWriteDeadline
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.writeDeadline = t
+	c.mu.Unlock()
+
+	notify(c.writable)
+	return nil
+}
+
+// Func notify - non-blocking signal to a notification channel
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// interface guard
+var _ net.Conn = (*Conn)(nil)
@@ -0,0 +1,547 @@
+package rudp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	mrand "math/rand/v2"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	ch3 "learn-network-programming/ch03-reliable-tcp-data-streams"
+)
+
+// lossyConn drops, duplicates and delays (reorders) outgoing datagrams
+type lossyConn struct {
+	net.PacketConn
+
+	mu  sync.Mutex
+	rnd *mrand.Rand
+}
+
+func newLossyConn(pc net.PacketConn, seed uint64) *lossyConn {
+	return &lossyConn{PacketConn: pc, rnd: mrand.New(mrand.NewPCG(seed, seed))}
+}
+
+func (l *lossyConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	l.mu.Lock()
+	r := l.rnd.Float64()
+	l.mu.Unlock()
+
+	switch {
+	// drop 20%
+	case r < 0.2:
+		return len(b), nil
+	// delay 10%, so that later datagrams overtake it
+	case r < 0.3:
+		delayed := append([]byte(nil), b...)
+		time.AfterFunc(5*time.Millisecond, func() {
+			_, _ = l.PacketConn.WriteTo(delayed, addr)
+		})
+		return len(b), nil
+	// duplicate 10%
+	case r < 0.4:
+		_, _ = l.PacketConn.WriteTo(b, addr)
+	}
+
+	return l.PacketConn.WriteTo(b, addr)
+}
+
+func TestPacketMarshalling(t *testing.T) {
+	packets := []packet{
+		{typ: typeData, seq: 42, payload: []byte("payload")},
+		{typ: typeAck, seq: 7, sack: 0b101},
+		{typ: typeFin, seq: 1 << 31},
+		{typ: typeSyn, seq: 0},
+	}
+
+	for i, expected := range packets {
+		b, err := expected.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+
+		var actual packet
+		if err := actual.UnmarshalBinary(b); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%d: expected %+v; actual %+v", i, expected, actual)
+		}
+	}
+
+	var p packet
+	if err := p.UnmarshalBinary([]byte{byte(typeAck), 0, 0}); err == nil {
+		t.Error("expected error for a truncated packet")
+	}
+}
+
+// runEcho accepts connections and echoes everything back
+func runEcho(listener net.Listener, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { _ = conn.Close() }()
+
+			_, _ = io.Copy(conn, conn)
+		}()
+	}
+}
+
+// transfer writes the payload and checks that it comes back unchanged
+func transfer(t *testing.T, conn net.Conn, size int) {
+	payload := make([]byte, size)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatal(err)
+	}
+
+	// write concurrently, since the echo comes back while we're writing
+	errs := make(chan error, 1)
+	go func() {
+		_, err := conn.Write(payload)
+		errs <- err
+	}()
+
+	_ = conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	echo := make([]byte, size)
+	if _, err := io.ReadFull(conn, echo); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(payload, echo) {
+		t.Fatal("echo doesn't match the payload")
+	}
+}
+
+func TestConnEcho(t *testing.T) {
+	listener, err := Listen("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go runEcho(listener, &wg)
+
+	conn, err := Dial("udp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transfer(t, conn, 1<<20)
+
+	// the server sees EOF after Close and closes its side too
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = listener.Close()
+	wg.Wait()
+}
+
+func TestConnLossy(t *testing.T) {
+	config := Config{RTO: 20 * time.Millisecond, MaxRetries: 50}
+
+	serverPC, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := config.NewListener(newLossyConn(serverPC, 1))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go runEcho(listener, &wg)
+
+	clientPC, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = clientPC.Close() }()
+
+	conn := config.NewConn(newLossyConn(clientPC, 2), serverPC.LocalAddr())
+
+	transfer(t, conn, 256<<10)
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = listener.Close()
+	wg.Wait()
+}
+
+func TestConnPing(t *testing.T) {
+	listener, err := Listen("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	// the dialing side pings, the same way it would over TCP
+	conn, err := Dial("udp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reset := make(chan time.Duration, 1)
+	reset <- 50 * time.Millisecond
+	go ch3.Ping(ctx, conn, reset)
+
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }()
+
+	_ = server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 4)
+	for i := 0; i < 3; i++ {
+		if _, err := io.ReadFull(server, buf); err != nil {
+			t.Fatal(err)
+		}
+
+		if string(buf) != "ping" {
+			t.Fatalf("expected %q; actual %q", "ping", buf)
+		}
+	}
+}
+
+func TestConnPeerUnreachable(t *testing.T) {
+	// nobody listens there
+	pc, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := pc.LocalAddr().String()
+	_ = pc.Close()
+
+	conn, err := Config{RTO: 20 * time.Millisecond, MaxRetries: 3}.Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.Write([]byte("hello?")); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	if !errors.Is(err, ErrPeerUnreachable) {
+		t.Fatalf("expected ErrPeerUnreachable; actual %v", err)
+	}
+}
+
+func TestConnReadDeadline(t *testing.T) {
+	listener, err := Listen("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	conn, err := Dial("udp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, err = conn.Read(make([]byte, 1))
+
+	var nErr net.Error
+	if !errors.As(err, &nErr) || !nErr.Timeout() {
+		t.Fatalf("expected timeout; actual %v", err)
+	}
+}
+
+func TestConnFlowControl(t *testing.T) {
+	// retransmissions alone would give up after 80 ms
+	config := Config{MaxPayload: 1024, Window: 8, ReadBuffer: 4096, RTO: 20 * time.Millisecond, MaxRetries: 3}
+
+	listener, err := config.Listen("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	conn, err := config.Dial("udp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	payload := make([]byte, 256<<10)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := conn.Write(payload)
+		errs <- err
+	}()
+
+	accepted, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := accepted.(*Conn)
+	defer func() { _ = server.Close() }()
+
+	// nobody reads: the buffer stops growing and the writer blocks,
+	// without the peer being declared unreachable
+	time.Sleep(300 * time.Millisecond)
+
+	server.mu.Lock()
+	buffered, held := server.readBuf.Len(), len(server.outOfOrder)
+	server.mu.Unlock()
+
+	if limit := config.ReadBuffer + config.MaxPayload; buffered > limit {
+		t.Errorf("expected at most %d bytes buffered; actual %d", limit, buffered)
+	}
+	if held > config.Window {
+		t.Errorf("expected at most %d datagrams held; actual %d", config.Window, held)
+	}
+
+	select {
+	case err := <-errs:
+		t.Fatalf("expected the write to block; actual %v", err)
+	default:
+	}
+
+	// reading lets the rest through
+	_ = server.SetReadDeadline(time.Now().Add(30 * time.Second))
+	received := make([]byte, len(payload))
+	if _, err := io.ReadFull(server, received); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(payload, received) {
+		t.Fatal("received data doesn't match the payload")
+	}
+}
+
+func TestConnCloseUnacknowledged(t *testing.T) {
+	// the server acknowledges a single datagram and never reads
+	config := Config{MaxPayload: 1024, Window: 4, ReadBuffer: 1024, RTO: 20 * time.Millisecond, MaxRetries: 3}
+
+	listener, err := config.Listen("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	conn, err := config.Dial("udp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Write(make([]byte, 3*1024)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.Close(); !errors.Is(err, ErrUnacknowledged) {
+		t.Fatalf("expected ErrUnacknowledged; actual %v", err)
+	}
+}
+
+func TestNewConnClose(t *testing.T) {
+	listener, err := Listen("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = pc.Close() }()
+
+	conn := Config{}.NewConn(pc, listener.Addr())
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the read loop is gone
+	select {
+	case <-conn.reading:
+	case <-time.After(time.Second):
+		t.Fatal("read loop still running")
+	}
+
+	// the socket stays usable without a deadline left behind
+	if _, err := pc.WriteTo([]byte("still open"), pc.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// late ACKs of the listener don't count
+		if addr.String() != pc.LocalAddr().String() {
+			continue
+		}
+
+		if actual := string(buf[:n]); actual != "still open" {
+			t.Errorf("expected %q; actual %q", "still open", actual)
+		}
+		break
+	}
+}
+
+func TestConnMaxPayloadMismatch(t *testing.T) {
+	small := Config{MaxPayload: 512, RTO: 20 * time.Millisecond, MaxRetries: 3}
+	large := Config{MaxPayload: 2048, RTO: 20 * time.Millisecond, MaxRetries: 3}
+
+	testCases := []struct {
+		name             string
+		listener, dialer Config
+		// whether the dialing side sends the large segment
+		dialerSends bool
+	}{
+		{"to the listener", small, large, true},
+		{"to the dialer", large, small, false},
+	}
+
+	for _, testCase := range testCases {
+		listener, err := testCase.listener.Listen("udp", "127.0.0.1:")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn, err := testCase.dialer.Dial("udp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a segment fitting both limits opens the connection
+		if _, err := conn.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+		server, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_ = server.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := io.ReadFull(server, make([]byte, 5)); err != nil {
+			t.Fatalf("%s: %v", testCase.name, err)
+		}
+
+		sender, receiver := net.Conn(server), net.Conn(conn)
+		if testCase.dialerSends {
+			sender, receiver = conn, server
+		}
+
+		// the large segment is dropped rather than delivered cut short
+		if _, err := sender.Write(bytes.Repeat([]byte("x"), 2000)); err != nil {
+			t.Fatalf("%s: %v", testCase.name, err)
+		}
+
+		_ = receiver.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+		n, err := receiver.Read(make([]byte, 4096))
+
+		var nErr net.Error
+		if !errors.As(err, &nErr) || !nErr.Timeout() {
+			t.Errorf("%s: expected timeout; actual %d bytes, %v", testCase.name, n, err)
+		}
+
+		// the sender gives up on the segment
+		_ = sender.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := sender.Read(make([]byte, 1)); !errors.Is(err, ErrPeerUnreachable) {
+			t.Errorf("%s: expected ErrPeerUnreachable; actual %v", testCase.name, err)
+		}
+
+		_ = conn.Close()
+		_ = server.Close()
+		_ = listener.Close()
+	}
+}
+
+func TestConnAcceptWithoutWrites(t *testing.T) {
+	config := Config{RTO: 20 * time.Millisecond, MaxRetries: 3}
+
+	listener, err := config.Listen("udp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	// a dialer that only reads
+	reader, err := config.Dial("udp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }()
+
+	if _, err := server.Write([]byte("welcome")); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = reader.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 7)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		t.Fatal(err)
+	}
+	if actual := string(buf); actual != "welcome" {
+		t.Errorf("expected %q; actual %q", "welcome", actual)
+	}
+
+	// a dialer that closes without writing
+	closer, err := config.Dial("udp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := closer.Close(); err != nil {
+		t.Errorf("expected clean close; actual %v", err)
+	}
+
+	server, err = listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }()
+
+	_ = server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := server.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("expected EOF; actual %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package rudp
+
+import (
+	"errors"
+	"learn-network-programming/ch06-ensuring-udp-reliability/datagram"
+	"net"
+	"sync"
+	"time"
+)
+
+// Size of the queue of connections waiting for Accept
+const acceptBacklog = 16
+
+// Listener accepts reliable connections on a single packet connection,
+// demultiplexing datagrams by the sender address.
+//
+// A new connection is created by the SYN a peer opens it with
+// (see Conn), there's no reply other than its ACK.
+//
+// Once a connection is closed, retransmissions of its peer are answered
+// with its last ACK for a while (like TCP's TIME-WAIT), so that the peer
+// can finish closing even if the ACK of its FIN was lost.
+type Listener struct {
+	config Config
+	pc     net.PacketConn
+
+	mu     sync.Mutex
+	conns  map[string]*Conn
+	closed bool
+	// Last ACKs of closed connections by the peer address
+	lingering map[string]lingeringAck
+
+	// Connections waiting for Accept
+	backlog chan *Conn
+	// Closed when the listener is closed
+	done chan struct{}
+}
+
+// struct lingeringAck - last ACK of a closed connection and when to forget it
+type lingeringAck struct {
+	ack   []byte
+	until time.Time
+}
+
+// Func NewListener - accept reliable connections over pc
+// (pc is closed once the listener and all its connections are closed)
+func (c Config) NewListener(pc net.PacketConn) *Listener {
+	l := &Listener{
+		config:    c.withDefaults(),
+		pc:        pc,
+		conns:     make(map[string]*Conn),
+		lingering: make(map[string]lingeringAck),
+		backlog:   make(chan *Conn, acceptBacklog),
+		done:      make(chan struct{}),
+	}
+
+	go l.readLoop()
+
+	return l
+}
+
+// Func readLoop - dispatch datagrams to connections, creating new ones
+func (l *Listener) readLoop() {
+	buf := newReadBuffer(l.config.MaxPayload)
+
+	for {
+		n, addr, err := readDatagram(l.pc, buf)
+		// Too large for the payload limit, the peer will fail to deliver it
+		if errors.Is(err, datagram.ErrTruncated) {
+			continue
+		}
+		if err != nil {
+			l.fail(err)
+			return
+		}
+
+		var p packet
+		if err := p.UnmarshalBinary(buf[:n]); err != nil {
+			continue
+		}
+
+		conn := l.lookup(addr, p)
+		if conn != nil {
+			conn.handle(p)
+		}
+	}
+}
+
+// Func lookup - find the connection of the sender, or create one
+// if it's the SYN of a new peer
+func (l *Listener) lookup(addr net.Addr, p packet) *Conn {
+	key := addr.String()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if conn, ok := l.conns[key]; ok {
+		return conn
+	}
+
+	// Retransmission to a closed connection: the ACK got lost
+	if linger, ok := l.lingering[key]; ok && time.Now().Before(linger.until) {
+		if p.typ != typeAck {
+			_, _ = l.pc.WriteTo(linger.ack, addr)
+		}
+		return nil
+	}
+
+	// Only SYN opens a connection, leftovers of closed connections are ignored
+	if l.closed || p.typ != typeSyn || p.seq != 0 {
+		return nil
+	}
+
+	// Ignore the peer if nobody accepts connections fast enough,
+	// it will retransmit anyway (only lookup fills the backlog,
+	// so there's room for the new connection afterwards)
+	if len(l.backlog) == cap(l.backlog) {
+		return nil
+	}
+
+	conn := newConn(l.config, l.pc, addr)
+	conn.onClose = func() { l.remove(key, conn.ack()) }
+	l.conns[key] = conn
+	l.backlog <- conn
+
+	return conn
+}
+
+// Func remove - forget a closed connection, keeping its last ACK for as long
+// as the peer may retransmit, release the socket if it was the last one
+func (l *Listener) remove(key string, ack []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.conns, key)
+
+	now := time.Now()
+	for k, linger := range l.lingering {
+		if now.After(linger.until) {
+			delete(l.lingering, k)
+		}
+	}
+	l.lingering[key] = lingeringAck{
+		ack:   ack,
+		until: now.Add(l.config.RTO * time.Duration(l.config.MaxRetries+1)),
+	}
+
+	if l.closed && len(l.conns) == 0 {
+		_ = l.pc.Close()
+	}
+}
+
+// Func fail - the socket is broken: fail all connections
+func (l *Listener) fail(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, conn := range l.conns {
+		conn.mu.Lock()
+		if !conn.closed {
+			conn.failLocked(err)
+		}
+		conn.mu.Unlock()
+	}
+}
+
+// Func Accept - wait for a new connection
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.backlog:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Func Close - stop accepting connections; already accepted ones keep working
+func (l *Listener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return net.ErrClosed
+	}
+	l.closed = true
+	close(l.done)
+
+	// Close connections nobody has accepted
+	var pending []*Conn
+	for {
+		select {
+		case conn := <-l.backlog:
+			pending = append(pending, conn)
+			continue
+		default:
+		}
+		break
+	}
+
+	if len(l.conns) == 0 {
+		_ = l.pc.Close()
+	}
+	l.mu.Unlock()
+
+	for _, conn := range pending {
+		go func(conn *Conn) { _ = conn.Close() }(conn)
+	}
+
+	return nil
+}
+
+// Func Addr
+func (l *Listener) Addr() net.Addr { return l.pc.LocalAddr() }
+
+// interface guard
+var _ net.Listener = (*Listener)(nil)
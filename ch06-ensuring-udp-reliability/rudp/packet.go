@@ -0,0 +1,128 @@
+package rudp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"learn-network-programming/ch06-ensuring-udp-reliability/datagram"
+	"net"
+)
+
+// Packet layout:
+//
+//	DATA, SYN, FIN: | type (1) | seq (4)           | payload (0..MaxPayload) |
+//	ACK:            | type (1) | next expected (4) | SACK bitmap (4)         |
+//
+// Bit i of the SACK bitmap is set if seq (next expected + 1 + i)
+// has been received out of order.
+const (
+	headerSize = 1 + 4
+	ackSize    = headerSize + 4
+	// Number of sequence numbers covered by the SACK bitmap
+	sackBits = 32
+)
+
+type packetType uint8
+
+const (
+	typeData packetType = iota + 1
+	typeAck
+	typeFin
+	typeSyn
+)
+
+var errInvalidPacket = errors.New("invalid packet")
+
+// struct packet
+type packet struct {
+	typ packetType
+	// sequence number (DATA, SYN, FIN) or next expected sequence number (ACK)
+	seq uint32
+	// selective acknowledgements (ACK only)
+	sack uint32
+	// data (DATA only)
+	payload []byte
+}
+
+// func MarshalBinary
+func (p packet) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.Grow(headerSize + len(p.payload) + 4)
+
+	err := buf.WriteByte(byte(p.typ))
+	if err != nil {
+		return nil, err
+	}
+
+	err = binary.Write(buf, binary.BigEndian, p.seq)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.typ {
+	case typeAck:
+		err = binary.Write(buf, binary.BigEndian, p.sack)
+	case typeData:
+		_, err = buf.Write(p.payload)
+	case typeFin, typeSyn:
+	default:
+		err = errInvalidPacket
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Func newReadBuffer - buffer for reading packets of up to maxPayload bytes,
+// with room for one more byte to tell larger datagrams apart
+func newReadBuffer(maxPayload int) []byte {
+	return make([]byte, headerSize+maxPayload+1)
+}
+
+// Func readDatagram - read a datagram into a buffer from newReadBuffer;
+// a larger datagram than the buffer is meant for (e.g., from a peer with
+// a larger MaxPayload) would be cut short and corrupt the stream once
+// acknowledged, so datagram.ErrTruncated is returned for it to be dropped
+func readDatagram(pc net.PacketConn, buf []byte) (int, net.Addr, error) {
+	n, addr, err := datagram.ReadFrom(pc, buf)
+	// Connections other than UDP and unixgram don't report truncation
+	if err == nil && n == len(buf) {
+		err = datagram.ErrTruncated
+	}
+
+	return n, addr, err
+}
+
+// func UnmarshalBinary (copies the payload, so b can be reused)
+func (p *packet) UnmarshalBinary(b []byte) error {
+	if len(b) < headerSize {
+		return errInvalidPacket
+	}
+
+	typ := packetType(b[0])
+	seq := binary.BigEndian.Uint32(b[1:headerSize])
+
+	switch typ {
+	case typeAck:
+		if len(b) != ackSize {
+			return errInvalidPacket
+		}
+		p.sack = binary.BigEndian.Uint32(b[headerSize:ackSize])
+	case typeData:
+		p.payload = append([]byte(nil), b[headerSize:]...)
+	case typeFin, typeSyn:
+		if len(b) != headerSize {
+			return errInvalidPacket
+		}
+	default:
+		return errInvalidPacket
+	}
+
+	p.typ = typ
+	p.seq = seq
+
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 var (
 	address = flag.String("a", "127.0.0.1:69", "listen address")
 	payload = flag.String("p", "payload.svg", "file to serve to clients")
+	maxSize = flag.Int("m", tftp.DatagramSize, "maximum request size in bytes")
 )
 
 func main() {
@@ -27,7 +28,7 @@ func main() {
 		log.Fatal(err)
 	}
 
-	s := tftp.Server{Payload: p}
+	s := tftp.Server{Payload: p, MaxDatagramSize: *maxSize}
 	err = s.Run(*address)
 	if err != nil {
 		log.Printf("Server finished with error: %v", err)
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"learn-network-programming/ch06-ensuring-udp-reliability/datagram"
 	"log"
 	"net"
 	"time"
@@ -14,6 +15,9 @@ type Server struct {
 	Payload []byte
 	Retries uint8
 	Timeout time.Duration
+	// Maximum size of a request, larger ones are rejected
+	// (DatagramSize if not set)
+	MaxDatagramSize int
 }
 
 func (s Server) Run(addr string) error {
@@ -33,20 +37,49 @@ func (s Server) Run(addr string) error {
 		return errors.New("payload is required")
 	}
 
-	return Server{Payload: payload, Retries: retries, Timeout: timeout}.listen(conn)
+	maxDatagramSize := s.MaxDatagramSize
+	if maxDatagramSize <= 0 {
+		maxDatagramSize = DatagramSize
+	}
+
+	return Server{
+		Payload:         payload,
+		Retries:         retries,
+		Timeout:         timeout,
+		MaxDatagramSize: maxDatagramSize,
+	}.listen(conn)
 }
 
 func (s Server) listen(conn net.PacketConn) error {
-	buf := make([]byte, DatagramSize)
+	buf := make([]byte, s.MaxDatagramSize)
+
+	for {
+		n, addr, err := datagram.ReadFrom(conn, buf)
+		if errors.Is(err, datagram.ErrTruncated) {
+			log.Printf("[%s] request larger than %d bytes", addr, len(buf))
+			rejectRequest(conn, addr, "request too large")
+			continue
+		}
+		if err != nil {
+			return err
+		}
 
-	_, addr, err := conn.ReadFrom(buf)
-	if err != nil {
-		return err
+		// The session outlives this read, so it gets its own copy of the request
+		go s.runSession(addr.String(), bytes.Clone(buf[:n]))
 	}
+}
 
-	go s.runSession(addr.String(), buf)
+func rejectRequest(conn net.PacketConn, addr net.Addr, errMsg string) {
+	data, err := Err{Error: ErrIllegalOp, Message: errMsg}.MarshalBinary()
+	if err != nil {
+		log.Printf("[%s] Err.MarshalBinary: %v", addr, err)
+		return
+	}
 
-	return s.listen(conn)
+	_, err = conn.WriteTo(data, addr)
+	if err != nil {
+		log.Printf("[%s] send error: %v", addr, err)
+	}
 }
 
 func (s Server) runSession(clientAddr string, msg []byte) {
@@ -0,0 +1,209 @@
+// Package activation implements the systemd socket activation protocol:
+// sockets inherited from a supervisor (LISTEN_FDS, LISTEN_PID, LISTEN_FDNAMES)
+// and readiness notifications sent to NOTIFY_SOCKET (sd_notify).
+package activation
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Inherited descriptors start after stdin, stdout and stderr
+const listenFdsStart = 3
+
+// Notification states
+const (
+	// The service finished starting up
+	Ready = "READY=1"
+	// The service is shutting down
+	Stopping = "STOPPING=1"
+)
+
+// Sockets passed by the supervisor, named with LISTEN_FDNAMES
+type Sockets struct {
+	listeners   []named[net.Listener]
+	packetConns []named[net.PacketConn]
+}
+
+// named socket
+type named[T any] struct {
+	name   string
+	socket T
+}
+
+// Sockets inherited by the process, loaded once
+var (
+	inheritedOnce sync.Once
+	inherited     *Sockets
+	inheritedErr  error
+)
+
+// Func Inherited - sockets passed to this process, loaded on the first call.
+// The environment variables are unset, so child processes don't take
+// the sockets for their own; without a supervisor, there are no sockets.
+func Inherited() (*Sockets, error) {
+	inheritedOnce.Do(func() {
+		inherited, inheritedErr = load(
+			os.Getenv("LISTEN_PID"),
+			os.Getenv("LISTEN_FDS"),
+			os.Getenv("LISTEN_FDNAMES"),
+			listenFdsStart,
+		)
+
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	})
+
+	return inherited, inheritedErr
+}
+
+// Func Listener - the first inherited stream listener with the name
+// ("" for any name), nil if there's none
+func Listener(name string) (net.Listener, error) {
+	s, err := Inherited()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Listener(name), nil
+}
+
+// Func PacketConn - the first inherited datagram socket with the name
+// ("" for any name), nil if there's none
+func PacketConn(name string) (net.PacketConn, error) {
+	s, err := Inherited()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.PacketConn(name), nil
+}
+
+// Func load - wrap the descriptors passed to the process with pid,
+// starting at the descriptor start
+func load(pid, fds, names string, start int) (*Sockets, error) {
+	s := new(Sockets)
+
+	// Not activated or the variables are meant for another process
+	if pid == "" || fds == "" || pid != strconv.Itoa(os.Getpid()) {
+		return s, nil
+	}
+
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("activation: invalid LISTEN_FDS %q", fds)
+	}
+
+	var fdNames []string
+	if names != "" {
+		fdNames = strings.Split(names, ":")
+	}
+
+	var errs []error
+	for i := 0; i < n; i++ {
+		name := ""
+		if i < len(fdNames) {
+			name = fdNames[i]
+		}
+
+		f := os.NewFile(uintptr(start+i), name)
+		if f == nil {
+			continue
+		}
+
+		if err := s.add(f); err != nil {
+			errs = append(errs, fmt.Errorf("activation: descriptor %d (%q): %w", start+i, name, err))
+		}
+
+		// The listener or connection uses a duplicate
+		_ = f.Close()
+	}
+
+	return s, errors.Join(errs...)
+}
+
+// Func add - wrap the descriptor as a listener or a datagram socket
+func (s *Sockets) add(f *os.File) error {
+	if l, err := net.FileListener(f); err == nil {
+		s.listeners = append(s.listeners, named[net.Listener]{f.Name(), l})
+		return nil
+	}
+
+	pc, err := net.FilePacketConn(f)
+	if err != nil {
+		return err
+	}
+
+	s.packetConns = append(s.packetConns, named[net.PacketConn]{f.Name(), pc})
+
+	return nil
+}
+
+// Func Listeners - all inherited stream listeners in the passed order
+func (s *Sockets) Listeners() []net.Listener {
+	listeners := make([]net.Listener, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		listeners = append(listeners, l.socket)
+	}
+
+	return listeners
+}
+
+// Func PacketConns - all inherited datagram sockets in the passed order
+func (s *Sockets) PacketConns() []net.PacketConn {
+	conns := make([]net.PacketConn, 0, len(s.packetConns))
+	for _, pc := range s.packetConns {
+		conns = append(conns, pc.socket)
+	}
+
+	return conns
+}
+
+// Func Listener - the first stream listener with the name ("" for any name)
+func (s *Sockets) Listener(name string) net.Listener {
+	for _, l := range s.listeners {
+		if name == "" || l.name == name {
+			return l.socket
+		}
+	}
+
+	return nil
+}
+
+// Func PacketConn - the first datagram socket with the name ("" for any name)
+func (s *Sockets) PacketConn(name string) net.PacketConn {
+	for _, pc := range s.packetConns {
+		if name == "" || pc.name == name {
+			return pc.socket
+		}
+	}
+
+	return nil
+}
+
+// Func Notify - send the states (e.g., Ready) to the supervisor,
+// false if the process isn't supervised (NOTIFY_SOCKET isn't set)
+func Notify(states ...string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("activation: notify: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return false, fmt.Errorf("activation: notify: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,132 @@
+package activation
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// testInherit - place the sockets' descriptors from start on,
+// like a supervisor does before exec
+func testInherit(t *testing.T, start int, sockets ...interface{ File() (*os.File, error) }) {
+	t.Helper()
+
+	for i, s := range sockets {
+		f, err := s.File()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := unix.Dup3(int(f.Fd()), start+i, unix.O_CLOEXEC); err != nil {
+			t.Fatal(err)
+		}
+		_ = f.Close()
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tcp, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tcp.Close() }()
+
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = udp.Close() }()
+
+	const start = 200
+	testInherit(t, start, tcp, udp)
+
+	s, err := load(strconv.Itoa(os.Getpid()), "2", "web:dns", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(s.Listeners()); n != 1 {
+		t.Fatalf("expected 1 listener; actual %d", n)
+	}
+	if n := len(s.PacketConns()); n != 1 {
+		t.Fatalf("expected 1 packet conn; actual %d", n)
+	}
+
+	l := s.Listener("web")
+	if l == nil {
+		t.Fatal("expected listener web")
+	}
+	defer func() { _ = l.Close() }()
+
+	if s.Listener("dns") != nil || s.PacketConn("web") != nil {
+		t.Error("expected sockets looked up by their names")
+	}
+
+	// The inherited listener accepts on the original address
+	conn, err := net.Dial("tcp", tcp.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+
+	accepted, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = accepted.Close()
+
+	pc := s.PacketConn("")
+	defer func() { _ = pc.Close() }()
+
+	if pc.LocalAddr().String() != udp.LocalAddr().String() {
+		t.Errorf("expected %s; actual %s", udp.LocalAddr(), pc.LocalAddr())
+	}
+}
+
+func TestLoadOtherProcess(t *testing.T) {
+	s, err := load(strconv.Itoa(os.Getpid()+1), "2", "", listenFdsStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Listeners()) != 0 || s.Listener("") != nil {
+		t.Error("expected no sockets for another process")
+	}
+
+	if _, err := load(strconv.Itoa(os.Getpid()), "two", "", listenFdsStart); err == nil {
+		t.Error("expected an error for invalid LISTEN_FDS")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if ok, err := Notify(Ready); ok || err != nil {
+		t.Errorf("expected no notification; actual %v, %v", ok, err)
+	}
+
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	supervisor, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = supervisor.Close() }()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	if ok, err := Notify(Ready, "STATUS=serving"); !ok || err != nil {
+		t.Fatalf("expected a notification; actual %v, %v", ok, err)
+	}
+
+	buf := make([]byte, 1024)
+	n, err := supervisor.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "READY=1\nSTATUS=serving"; string(buf[:n]) != expected {
+		t.Errorf("expected %q; actual %q", expected, buf[:n])
+	}
+}
@@ -1,15 +1,34 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"learn-network-programming/ch07-unix-domain-sockets/auth"
+	"learn-network-programming/ch07-unix-domain-sockets/activation"
+	"learn-network-programming/ch07-unix-domain-sockets/ctl"
+	"learn-network-programming/ch07-unix-domain-sockets/unixsock"
 	"log"
 	"net"
 	"os"
 	"os/signal"
 	"os/user"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Command to send to a running server instead of serving
+var call = flag.String("call", "", `command to run on the server, e.g., "set-log-level debug"`)
+
+// Socket path ("@name" for a Linux abstract socket), its permissions and group
+var (
+	socket = flag.String("socket", filepath.Join(os.TempDir(), "creds.sock"), `socket path, "@name" for an abstract socket`)
+	mode   = flag.String("mode", "", "octal permissions of the socket file, e.g., 0660")
+	group  = flag.String("group", "", "group name or id owning the socket file")
 )
 
 // Func init
@@ -19,7 +38,7 @@ func init() {
 	flag.Usage = func() {
 		fmt.Fprintf(
 			flag.CommandLine.Output(),
-			"Usage:\n\t%s <group names>\n",
+			"Usage:\n\t%[1]s <admin group names>\n\t%[1]s -call <command>\n",
 			filepath.Base(os.Args[0]),
 		)
 		flag.PrintDefaults()
@@ -50,84 +69,118 @@ func main() {
 	// Parse command line options
 	flag.Parse()
 
-	// Parse group ids using all given non-flag command line options
-	groups := parseGroupIds(flag.Args())
-	// create a new socket file "creds.sock" in the temp dir
-	socket := filepath.Join(os.TempDir(), "creds.sock")
-	// resolve the socket filename into a net.Addr
-	addr, err := net.ResolveUnixAddr("unix", socket)
+	// Client mode: run the command and print its output
+	if *call != "" {
+		if err := runCommand(*socket, *call); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Parse group ids using all given non-flag command line options,
+	// only members of these groups may change the server
+	admins := parseGroupIds(flag.Args())
+
+	// Logger with the level adjustable through the control socket
+	config := zap.NewProductionConfig()
+	logger, err := config.Build()
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer func() { _ = logger.Sync() }()
 
-	// Create a server on the resolved address
-	server, err := net.ListenUnix("unix", addr)
+	opts := unixsock.Options{Group: *group}
+	if *mode != "" {
+		perm, err := strconv.ParseUint(*mode, 8, 32)
+		if err != nil {
+			log.Fatalf("invalid mode %q: %v", *mode, err)
+		}
+		opts.Mode = os.FileMode(perm)
+	}
+
+	// Use the socket passed by the supervisor (systemd socket activation),
+	// it owns the socket file then
+	server, err := activation.Listener("")
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if server == nil {
+		// Create a server on the socket, replacing a stale socket file
+		// left by a crashed server; the file is removed on close
+		server, err = unixsock.ListenUnix("unix", *socket, opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	control := newControlServer(admins, config.Level, logger)
+
 	// Create a channel to listen for interrupts, to do proper cleanup on termination
 	// capacity is 1 not to block a signal sender
 	c := make(chan os.Signal, 1)
-	// Redirect interrupt signal to the channel
-	signal.Notify(c, os.Interrupt)
+	// Redirect interrupt and termination signals to the channel
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	// Listen asynchronously, close server on notification
 	go func() {
 		<-c
-		log.Print("Interrupt sig received. Shutting down...")
-		_ = server.Close()
+		logger.Info("interrupt signal received, shutting down")
+		_, _ = activation.Notify(activation.Stopping)
+		_ = control.Close()
 	}()
 
-	// Report litening start
-	log.Printf("Listening on %s ...\n", socket)
+	// Report litening start, tell the supervisor (if any) we're ready
+	logger.Info("listening", zap.Stringer("socket", server.Addr()))
+	if _, err := activation.Notify(activation.Ready); err != nil {
+		logger.Warn("notify", zap.Error(err))
+	}
 
-	// Accept connections
-	_ = acceptConnections(server, groups)
+	// Serve commands until interrupted
+	err = control.Serve(server)
+	if !errors.Is(err, net.ErrClosed) {
+		logger.Error("serve", zap.Error(err))
+	}
 }
 
-// Func accept connections
-func acceptConnections(server *net.UnixListener, groups map[string]struct{}) error {
-	// Accept new unix domain socket client
-	client, err := server.AcceptUnix()
-	if err != nil {
-		return err
-	}
+// Func newControlServer - built-in commands, the ones changing
+// the server are restricted to the admin groups
+func newControlServer(admins map[string]struct{}, level zap.AtomicLevel, logger *zap.Logger) *ctl.Server {
+	s := ctl.NewServer()
 
-	// Run client session asynchronously
-	go clientSession(client, groups)
+	s.Handle(ctl.StatusCommand(time.Now(), nil))
 
-	// Keep accepting new clients
-	return acceptConnections(server, groups)
-}
+	reload := ctl.ReloadCommand(func() error {
+		logger.Info("configuration reloaded")
+		return nil
+	})
+	reload.Groups = admins
+	s.Handle(reload)
 
-// Func clientSession
-func clientSession(client *net.UnixConn, groups map[string]struct{}) {
-	// Close client on scope exit
-	defer func() {
-		_ = client.Close()
-	}()
+	setLevel := ctl.LogLevelCommand(level)
+	setLevel.Groups = admins
+	s.Handle(setLevel)
 
-	// Additional wrap to handle errors
-	err := doRunClientSession(client, groups)
-	if err != nil {
-		log.Printf("[%s] error: %v", client.RemoteAddr().String(), err)
-	}
+	return s
 }
 
-// Func doRunClientSession
-func doRunClientSession(client *net.UnixConn, groups map[string]struct{}) error {
-	// Check if the client has required permissions
-	allowed, err := auth.Allowed(client, groups)
+// Func runCommand - send a single command to the server and print its output
+func runCommand(socket, command string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return errors.New("empty command")
+	}
+
+	client, err := ctl.Dial(socket)
 	if err != nil {
 		return err
 	}
+	defer func() { _ = client.Close() }()
 
-	// Send welcome or access denied messages depending on the check
-	if allowed {
-		_, err = client.Write([]byte("Welcome\n"))
-	} else {
-		_, err = client.Write([]byte("Access denied\n"))
+	output, err := client.Call(fields[0], fields[1:]...)
+	if err != nil {
+		return err
 	}
 
-	return err
+	fmt.Print(output)
+	return nil
 }
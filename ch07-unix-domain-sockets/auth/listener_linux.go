@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Listener accepts unix connections from the peers allowed by the policy
+// only; the others are closed before Accept returns.
+// Accepted connections are *Conn, carrying the peer credentials.
+type Listener struct {
+	*net.UnixListener
+	Policy Policy
+	// Called with the connection of a rejected peer (e.g., to explain
+	// why, or to log it) right before it's closed; err wraps ErrDenied
+	// or tells why the credentials couldn't be read
+	OnReject func(conn *net.UnixConn, creds Creds, err error)
+}
+
+// Func NewListener - wrap the listener to enforce the policy
+func NewListener(l *net.UnixListener, policy Policy) *Listener {
+	return &Listener{UnixListener: l, Policy: policy}
+}
+
+// Conn is an accepted connection of an allowed peer
+type Conn struct {
+	*net.UnixConn
+	creds Creds
+}
+
+// Func Creds - credentials of the peer, as of connecting
+func (c *Conn) Creds() Creds { return c.creds }
+
+// Func CredsOf - peer credentials of a connection accepted by Listener
+func CredsOf(conn net.Conn) (Creds, bool) {
+	c, ok := conn.(*Conn)
+	if !ok {
+		return Creds{}, false
+	}
+
+	return c.creds, true
+}
+
+// Func Accept - wait for the next allowed peer
+func (l *Listener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.AcceptUnix()
+		if err != nil {
+			return nil, err
+		}
+
+		creds, err := l.authorize(conn)
+		if err != nil {
+			if l.OnReject != nil {
+				l.OnReject(conn, creds, err)
+			}
+			_ = conn.Close()
+			continue
+		}
+
+		return &Conn{UnixConn: conn, creds: creds}, nil
+	}
+}
+
+// Func authorize - read the peer credentials and check them against the policy
+func (l *Listener) authorize(conn *net.UnixConn) (Creds, error) {
+	creds, err := PeerCreds(conn)
+	if err != nil {
+		return creds, fmt.Errorf("peer credentials: %w", err)
+	}
+
+	if l.Policy.needsExe() {
+		// An unresolved executable doesn't match any path
+		creds.Exe, _ = Executable(creds.PID)
+	}
+
+	return creds, l.Policy.Check(creds)
+}
+
+// Func PeerCreds - SO_PEERCRED of the connection: credentials
+// of the peer process at the time it connected
+func PeerCreds(conn *net.UnixConn) (Creds, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return Creds{}, err
+	}
+
+	var ucred *unix.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		// Retry if interrupted
+		for {
+			ucred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+			if credErr != unix.EINTR {
+				return
+			}
+		}
+	})
+	if err != nil {
+		return Creds{}, err
+	}
+	if credErr != nil {
+		return Creds{}, credErr
+	}
+
+	return Creds{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid}, nil
+}
+
+// Func Executable - path of the process executable, from /proc/<pid>/exe
+// (reading it requires the same user as the process, or CAP_SYS_PTRACE)
+func Executable(pid int32) (string, error) {
+	exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		return "", err
+	}
+
+	// The binary was replaced or removed since the process started
+	if strings.HasSuffix(exe, " (deleted)") {
+		return "", fmt.Errorf("executable of pid %d was deleted", pid)
+	}
+
+	return exe, nil
+}
+
+// interface guards
+var (
+	_ net.Listener = (*Listener)(nil)
+	_ net.Conn     = (*Conn)(nil)
+)
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testListen - unix listener in a temp dir, wrapped with the policy
+func testListen(t *testing.T, policy Policy) *Listener {
+	t.Helper()
+
+	addr := &net.UnixAddr{Name: filepath.Join(t.TempDir(), "auth.sock"), Net: "unix"}
+	l, err := net.ListenUnix("unix", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	return NewListener(l, policy)
+}
+
+func TestListenerAllowed(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := testListen(t, Policy{
+		UIDs: []uint32{uint32(os.Getuid())},
+		PIDs: []int32{int32(os.Getpid())},
+		Exes: []string{exe},
+	})
+
+	client, err := net.Dial("unix", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	creds, ok := CredsOf(conn)
+	if !ok {
+		t.Fatalf("expected *Conn; actual %T", conn)
+	}
+
+	expected := Creds{
+		PID: int32(os.Getpid()),
+		UID: uint32(os.Getuid()),
+		GID: uint32(os.Getgid()),
+		Exe: exe,
+	}
+	if creds != expected {
+		t.Errorf("expected %+v; actual %+v", expected, creds)
+	}
+}
+
+func TestListenerRejected(t *testing.T) {
+	l := testListen(t, Policy{UIDs: []uint32{uint32(os.Getuid()) + 1}})
+
+	rejected := make(chan error, 1)
+	l.OnReject = func(conn *net.UnixConn, _ Creds, err error) {
+		_, _ = conn.Write([]byte("Access denied\n"))
+		rejected <- err
+	}
+
+	// Accept never returns the rejected peer
+	accepted := make(chan error, 1)
+	go func() {
+		_, err := l.Accept()
+		accepted <- err
+	}()
+
+	client, err := net.Dial("unix", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+
+	select {
+	case err := <-rejected:
+		if !errors.Is(err, ErrDenied) {
+			t.Fatalf("expected ErrDenied; actual %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("peer wasn't rejected")
+	}
+
+	// The client gets the explanation, then the connection is closed
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	reply, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(reply) != "Access denied\n" {
+		t.Errorf("expected %q; actual %q", "Access denied\n", reply)
+	}
+
+	_ = l.Close()
+	if err := <-accepted; !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("expected net.ErrClosed; actual %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// ErrDenied is returned (wrapped, with the reason) for peers
+// that don't satisfy the policy
+var ErrDenied = errors.New("access denied")
+
+// Creds are credentials of the process on the other side of a unix socket
+type Creds struct {
+	PID int32
+	UID uint32
+	GID uint32
+	// Path of the peer's executable, resolved only if the policy needs it
+	// (empty otherwise, or if it couldn't be resolved)
+	Exe string
+}
+
+// Policy lists allowed peers: a peer must match every non-empty list
+// (any entry of it), the zero Policy allows everybody
+type Policy struct {
+	UIDs []uint32
+	// Primary or supplementary groups of the peer's user
+	GIDs []uint32
+	// Mind that PIDs get reused, so a PID only identifies a long-running process
+	PIDs []int32
+	// Absolute paths of the executables
+	Exes []string
+}
+
+// Func needsExe - whether the executable path has to be resolved
+func (p Policy) needsExe() bool { return len(p.Exes) > 0 }
+
+// Func Check - nil if the peer is allowed, ErrDenied with the reason otherwise
+func (p Policy) Check(c Creds) error {
+	if len(p.UIDs) > 0 && !slices.Contains(p.UIDs, c.UID) {
+		return fmt.Errorf("%w: uid %d", ErrDenied, c.UID)
+	}
+
+	if len(p.PIDs) > 0 && !slices.Contains(p.PIDs, c.PID) {
+		return fmt.Errorf("%w: pid %d", ErrDenied, c.PID)
+	}
+
+	if len(p.GIDs) > 0 && !p.groupAllowed(c) {
+		return fmt.Errorf("%w: uid %d is not a member of allowed groups", ErrDenied, c.UID)
+	}
+
+	if p.needsExe() && !slices.ContainsFunc(p.Exes, func(exe string) bool {
+		return c.Exe != "" && filepath.Clean(exe) == c.Exe
+	}) {
+		return fmt.Errorf("%w: executable %q", ErrDenied, c.Exe)
+	}
+
+	return nil
+}
+
+// Func groupAllowed - whether the peer's primary group
+// or one of its user's groups is allowed
+func (p Policy) groupAllowed(c Creds) bool {
+	if slices.Contains(p.GIDs, c.GID) {
+		return true
+	}
+
+	// Supplementary groups of the user, same as Allowed
+	usr, err := user.LookupId(strconv.FormatUint(uint64(c.UID), 10))
+	if err != nil {
+		return false
+	}
+
+	gids, err := usr.GroupIds()
+	if err != nil {
+		return false
+	}
+
+	return slices.ContainsFunc(gids, func(gid string) bool {
+		id, err := strconv.ParseUint(gid, 10, 32)
+		return err == nil && slices.Contains(p.GIDs, uint32(id))
+	})
+}
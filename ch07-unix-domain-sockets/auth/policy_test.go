@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	creds := Creds{PID: 42, UID: 1000, GID: 1000, Exe: "/usr/bin/app"}
+
+	for i, c := range []struct {
+		policy  Policy
+		allowed bool
+	}{
+		{Policy{}, true},
+		{Policy{UIDs: []uint32{0, 1000}}, true},
+		{Policy{UIDs: []uint32{0}}, false},
+		{Policy{GIDs: []uint32{1000}}, true},
+		{Policy{PIDs: []int32{42}}, true},
+		{Policy{PIDs: []int32{43}}, false},
+		{Policy{Exes: []string{"/usr/bin/../bin/app"}}, true},
+		{Policy{Exes: []string{"/usr/bin/other"}}, false},
+		// every list has to match
+		{Policy{UIDs: []uint32{1000}, PIDs: []int32{43}}, false},
+		{Policy{UIDs: []uint32{1000}, Exes: []string{"/usr/bin/app"}}, true},
+	} {
+		err := c.policy.Check(creds)
+		if c.allowed && err != nil {
+			t.Errorf("%d: expected allowed; actual %v", i, err)
+		}
+		if !c.allowed && !errors.Is(err, ErrDenied) {
+			t.Errorf("%d: expected ErrDenied; actual %v", i, err)
+		}
+	}
+
+	// an unresolved executable never matches
+	err := Policy{Exes: []string{""}}.Check(Creds{})
+	if !errors.Is(err, ErrDenied) {
+		t.Errorf("expected ErrDenied; actual %v", err)
+	}
+}
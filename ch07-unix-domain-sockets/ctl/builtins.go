@@ -0,0 +1,84 @@
+package ctl
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Func StatusCommand - "status": process id, uptime, goroutines,
+// and the service-specific values from status (may be nil)
+func StatusCommand(start time.Time, status func() map[string]string) Command {
+	return Command{
+		Name: "status",
+		Help: "show the service status",
+		Handler: func(_ []string) (string, error) {
+			var b strings.Builder
+			fmt.Fprintf(&b, "pid: %d\n", os.Getpid())
+			fmt.Fprintf(&b, "uptime: %s\n", time.Since(start).Round(time.Second))
+			fmt.Fprintf(&b, "goroutines: %d\n", runtime.NumGoroutine())
+			fmt.Fprintf(&b, "go: %s\n", runtime.Version())
+
+			if status == nil {
+				return b.String(), nil
+			}
+
+			values := status()
+			keys := make([]string, 0, len(values))
+			for key := range values {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+
+			for _, key := range keys {
+				fmt.Fprintf(&b, "%s: %s\n", key, values[key])
+			}
+
+			return b.String(), nil
+		},
+	}
+}
+
+// Func ReloadCommand - "reload": reload the configuration with the function
+func ReloadCommand(reload func() error) Command {
+	return Command{
+		Name: "reload",
+		Help: "reload the configuration",
+		Handler: func(_ []string) (string, error) {
+			if err := reload(); err != nil {
+				return "", err
+			}
+
+			return "reloaded\n", nil
+		},
+	}
+}
+
+// Func LogLevelCommand - "set-log-level <level>": change the level of loggers
+// using it; without an argument, show the current level
+func LogLevelCommand(level zap.AtomicLevel) Command {
+	return Command{
+		Name: "set-log-level",
+		Help: "set-log-level [debug|info|warn|error]: change (or show) the log level",
+		Handler: func(args []string) (string, error) {
+			switch len(args) {
+			case 0:
+				return level.String() + "\n", nil
+			case 1:
+			default:
+				return "", fmt.Errorf("expected a single level, got %d arguments", len(args))
+			}
+
+			if err := level.UnmarshalText([]byte(args[0])); err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("log level set to %s\n", level), nil
+		},
+	}
+}
@@ -0,0 +1,53 @@
+package ctl
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Client sends commands to a control server over a single connection
+type Client struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Func Dial - connect to the control socket
+func Dial(socket string) (*Client, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClient(conn), nil
+}
+
+// Func NewClient - client over an established connection
+func NewClient(conn net.Conn) *Client {
+	return &Client{conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// Func Call - run the command and return its output; errors reported
+// by the command (including authorization) are *CommandError
+func (c *Client) Call(command string, args ...string) (string, error) {
+	if command == "" || strings.ContainsAny(command, " \n") {
+		return "", errors.New("ctl: invalid command name")
+	}
+
+	request := strings.Join(append([]string{command}, args...), " ")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := writeFrame(c.conn, request); err != nil {
+		return "", err
+	}
+
+	return readResponse(c.reader)
+}
+
+// Func Close
+func (c *Client) Close() error { return c.conn.Close() }
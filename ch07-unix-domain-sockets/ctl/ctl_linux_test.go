@@ -0,0 +1,136 @@
+package ctl
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// testServe - serve the server on a socket in a temp dir, connect a client
+func testServe(t *testing.T, s *Server) *Client {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "ctl.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(l) }()
+	t.Cleanup(func() {
+		_ = s.Close()
+		if err := <-done; !errors.Is(err, net.ErrClosed) {
+			t.Errorf("expected net.ErrClosed; actual %v", err)
+		}
+	})
+
+	client, err := Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+func TestBuiltins(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	reloads := 0
+
+	s := NewServer()
+	s.Handle(StatusCommand(time.Now(), func() map[string]string {
+		return map[string]string{"connections": "3"}
+	}))
+	s.Handle(ReloadCommand(func() error {
+		reloads++
+		return nil
+	}))
+	s.Handle(LogLevelCommand(level))
+
+	client := testServe(t, s)
+
+	status, err := client.Call("status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"pid: " + strconv.Itoa(os.Getpid()), "connections: 3"} {
+		if !strings.Contains(status, line+"\n") {
+			t.Errorf("expected %q in status %q", line, status)
+		}
+	}
+
+	if _, err := client.Call("reload"); err != nil {
+		t.Fatal(err)
+	}
+	if reloads != 1 {
+		t.Errorf("expected 1 reload; actual %d", reloads)
+	}
+
+	if _, err := client.Call("set-log-level", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if level.Level() != zapcore.DebugLevel {
+		t.Errorf("expected debug level; actual %s", level.Level())
+	}
+
+	var cmdErr *CommandError
+	if _, err := client.Call("set-log-level", "chatty"); !errors.As(err, &cmdErr) {
+		t.Errorf("expected CommandError; actual %v", err)
+	}
+
+	help, err := client.Call("help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"help", "reload", "set-log-level", "status"} {
+		if !strings.Contains(help, name+"\t") {
+			t.Errorf("expected %q in help %q", name, help)
+		}
+	}
+
+	if _, err := client.Call("shutdown"); !errors.As(err, &cmdErr) {
+		t.Errorf("expected CommandError for unknown command; actual %v", err)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	ok := func(_ []string) (string, error) { return "done", nil }
+
+	s := NewServer()
+	s.Handle(Command{
+		Name:    "mine",
+		Groups:  map[string]struct{}{strconv.Itoa(os.Getgid()): {}},
+		Handler: ok,
+	})
+	s.Handle(Command{
+		Name:    "theirs",
+		Groups:  map[string]struct{}{"4294967294": {}},
+		Handler: ok,
+	})
+
+	client := testServe(t, s)
+
+	if output, err := client.Call("mine"); err != nil || output != "done" {
+		t.Errorf("expected %q; actual %q, %v", "done", output, err)
+	}
+
+	_, err := client.Call("theirs")
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Message != ErrPermissionDenied.Error() {
+		t.Errorf("expected %q; actual %v", ErrPermissionDenied, err)
+	}
+
+	// The connection survives a denied command
+	if _, err := client.Call("help"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+// Package ctl is a local control plane: commands sent over a unix socket,
+// authorized per command by the peer credentials.
+//
+// Every request and response is a single ch4 TLV frame (type, length, value)
+// with a String payload. A request is the command name followed by its
+// arguments, separated by spaces. A response is "ok" or "error", a newline,
+// and the command output or the error message.
+package ctl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	ch4 "learn-network-programming/ch04-sending-tcp-data"
+)
+
+// Response statuses
+const (
+	statusOK    = "ok"
+	statusError = "error"
+)
+
+// Func writeFrame - send the text as a String TLV frame
+func writeFrame(w io.Writer, text string) error {
+	s := ch4.String(text)
+	_, err := ch4.Encode(w, &s)
+
+	return err
+}
+
+// Func readFrame - receive a String TLV frame
+func readFrame(r io.Reader) (string, error) {
+	p, _, err := ch4.Decode(r)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := p.(*ch4.String)
+	if !ok {
+		return "", errors.New("ctl: expected a string frame")
+	}
+
+	return string(*s), nil
+}
+
+// Func writeResponse - send the command output or its error
+func writeResponse(w io.Writer, output string, err error) error {
+	if err != nil {
+		return writeFrame(w, statusError+"\n"+err.Error())
+	}
+
+	return writeFrame(w, statusOK+"\n"+output)
+}
+
+// Func readResponse - receive the command output or its error
+func readResponse(r io.Reader) (string, error) {
+	frame, err := readFrame(r)
+	if err != nil {
+		return "", err
+	}
+
+	status, body, _ := strings.Cut(frame, "\n")
+	switch status {
+	case statusOK:
+		return body, nil
+	case statusError:
+		return "", &CommandError{Message: body}
+	}
+
+	return "", fmt.Errorf("ctl: invalid response status %q", status)
+}
+
+// CommandError is an error reported by the server, as opposed to
+// connection or protocol errors
+type CommandError struct {
+	Message string
+}
+
+// Func Error
+func (e *CommandError) Error() string { return e.Message }
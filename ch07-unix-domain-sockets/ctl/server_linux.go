@@ -0,0 +1,219 @@
+package ctl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"learn-network-programming/ch07-unix-domain-sockets/auth"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrPermissionDenied is reported to peers that aren't allowed to run a command
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Handler runs a command with the arguments and returns its output
+type Handler func(args []string) (string, error)
+
+// Command is a named handler with its authorization
+type Command struct {
+	Name string
+	// One-line description shown by help
+	Help string
+	// Ids of the groups allowed to run the command (checked with
+	// auth.Allowed), nil allows everybody who can connect to the socket
+	Groups  map[string]struct{}
+	Handler Handler
+}
+
+// Server runs commands received on unix connections
+type Server struct {
+	mu       sync.Mutex
+	commands map[string]Command
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closing  bool
+	wg       sync.WaitGroup
+}
+
+// Func NewServer - server with the help command only
+func NewServer() *Server {
+	s := &Server{
+		commands: make(map[string]Command),
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	s.Handle(Command{Name: "help", Help: "list commands", Handler: s.help})
+
+	return s
+}
+
+// Func Handle - register the command, replacing one with the same name
+func (s *Server) Handle(cmd Command) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commands[cmd.Name] = cmd
+}
+
+// Func help - names and descriptions of the commands
+func (s *Server) help(_ []string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.commands))
+	for name := range s.commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s\t%s\n", name, s.commands[name].Help)
+	}
+
+	return b.String(), nil
+}
+
+// Func Serve - accept unix connections until Close,
+// returns net.ErrClosed after Close
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return net.ErrClosed
+	}
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		if !s.track(conn) {
+			_ = conn.Close()
+			return net.ErrClosed
+		}
+
+		go func() {
+			defer s.untrack(conn)
+			s.serveConn(conn)
+		}()
+	}
+}
+
+// Func Close - stop accepting, close connections and wait for running commands
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closing = true
+
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	return err
+}
+
+// Func track - register a connection (false if the server is closing)
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return false
+	}
+
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+
+	return true
+}
+
+// Func untrack - close and unregister a finished connection
+func (s *Server) untrack(conn net.Conn) {
+	_ = conn.Close()
+
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+
+	s.wg.Done()
+}
+
+// Func serveConn - run commands until the peer disconnects
+func (s *Server) serveConn(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+
+	for {
+		request, err := readFrame(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				_ = writeResponse(conn, "", err)
+			}
+			return
+		}
+
+		output, err := s.run(conn, request)
+		if err := writeResponse(conn, output, err); err != nil {
+			return
+		}
+	}
+}
+
+// Func run - authorize and run a single command
+func (s *Server) run(conn net.Conn, request string) (string, error) {
+	fields := strings.Fields(request)
+	if len(fields) == 0 {
+		return "", errors.New("empty command")
+	}
+
+	s.mu.Lock()
+	cmd, ok := s.commands[fields[0]]
+	s.mu.Unlock()
+
+	if !ok {
+		return "", fmt.Errorf("unknown command %q, try help", fields[0])
+	}
+
+	if cmd.Groups != nil {
+		uc, ok := unixConn(conn)
+		if !ok {
+			return "", ErrPermissionDenied
+		}
+
+		allowed, err := auth.Allowed(uc, cmd.Groups)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrPermissionDenied, err)
+		}
+		if !allowed {
+			return "", ErrPermissionDenied
+		}
+	}
+
+	return cmd.Handler(fields[1:])
+}
+
+// Func unixConn - the unix connection, also the one wrapped by auth.Listener
+func unixConn(conn net.Conn) (*net.UnixConn, bool) {
+	switch c := conn.(type) {
+	case *net.UnixConn:
+		return c, true
+	case *auth.Conn:
+		return c.UnixConn, true
+	}
+
+	return nil, false
+}
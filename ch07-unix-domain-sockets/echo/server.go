@@ -0,0 +1,344 @@
+package echo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learn-network-programming/ch06-ensuring-udp-reliability/datagram"
+	"net"
+	"sync"
+)
+
+// DefaultBufferSize is used when Server.BufferSize is not set
+const DefaultBufferSize = 1024
+
+// ErrServerClosed is returned by Serve after Shutdown or Close
+var ErrServerClosed = errors.New("echo: server closed")
+
+// Server echoes everything it receives back to the sender.
+//
+// Streaming networks (tcp, tcp4, tcp6, unix, unixpacket) are served
+// with a session per connection, datagram networks (udp, udp4, udp6,
+// unixgram) with a single read-write loop.
+type Server struct {
+	// Network and address to listen on
+	Network string
+	Address string
+	// Size of the read buffer, per session for streaming networks
+	// (DefaultBufferSize if not set); for datagram networks it's
+	// the maximum datagram size
+	BufferSize int
+	// Called for datagrams larger than BufferSize, which are dropped
+	// instead of echoing them truncated (detected on unix systems only)
+	OnTruncated func(from net.Addr)
+	// Maximum number of concurrent connections for streaming networks:
+	// the server stops accepting until one of them is closed
+	// (unlimited if not set)
+	MaxConns int
+
+	mu sync.Mutex
+	// Either a listener (streaming) or a packet connection (datagram)
+	listener   net.Listener
+	packetConn net.PacketConn
+	// Open connections of streaming sessions
+	conns map[net.Conn]struct{}
+	// Set (and quit closed) once the server is shutting down
+	closing bool
+	quit    chan struct{}
+	// Goroutines serving sessions (or the datagram loop)
+	wg sync.WaitGroup
+}
+
+// Func isStreaming - whether the network is connection oriented
+func isStreaming(network string) (bool, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix", "unixpacket":
+		return true, nil
+	case "udp", "udp4", "udp6", "unixgram":
+		return false, nil
+	}
+
+	return false, net.UnknownNetworkError(network)
+}
+
+// Func Listen - bind to the address, Addr is available afterwards
+func (s *Server) Listen() error {
+	streaming, err := isStreaming(s.Network)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil || s.packetConn != nil {
+		return errors.New("echo: already listening")
+	}
+
+	s.quit = make(chan struct{})
+
+	if streaming {
+		s.listener, err = net.Listen(s.Network, s.Address)
+	} else {
+		s.packetConn, err = net.ListenPacket(s.Network, s.Address)
+	}
+
+	if err != nil {
+		return fmt.Errorf("binding to %s %s: %w", s.Network, s.Address, err)
+	}
+
+	return nil
+}
+
+// Func Addr - address the server listens on (nil before Listen)
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.listener != nil:
+		return s.listener.Addr()
+	case s.packetConn != nil:
+		return s.packetConn.LocalAddr()
+	}
+
+	return nil
+}
+
+// Func ListenAndServe - Listen and Serve in one go
+func (s *Server) ListenAndServe() error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+
+	return s.Serve()
+}
+
+// Func Serve - serve clients until Shutdown or Close,
+// always returns a non-nil error (ErrServerClosed after a shutdown)
+func (s *Server) Serve() error {
+	s.mu.Lock()
+	listener, packetConn := s.listener, s.packetConn
+	s.mu.Unlock()
+
+	switch {
+	case listener != nil:
+		return s.serveStream(listener)
+	case packetConn != nil:
+		return s.servePackets(packetConn)
+	}
+
+	return errors.New("echo: not listening")
+}
+
+// Func Shutdown - stop accepting new clients and wait for the sessions to finish;
+// when the context is done, close the remaining sessions and return its error
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	err := s.closeListenersLocked()
+	s.mu.Unlock()
+
+	// Wait for sessions in the background
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		// Out of time: interrupt the remaining sessions
+		s.closeConns()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Func Close - close the server and all sessions immediately
+// and wait for their goroutines to exit
+func (s *Server) Close() error {
+	s.mu.Lock()
+	err := s.closeListenersLocked()
+	s.mu.Unlock()
+
+	s.closeConns()
+	s.wg.Wait()
+
+	return err
+}
+
+// Func closeListenersLocked - mark the server as closing and close
+// the listener or the packet connection, must be called under lock
+func (s *Server) closeListenersLocked() error {
+	// Shutdown and Close may both be called
+	if s.closing {
+		return nil
+	}
+
+	s.closing = true
+	if s.quit != nil {
+		close(s.quit)
+	}
+
+	switch {
+	case s.listener != nil:
+		return s.listener.Close()
+	case s.packetConn != nil:
+		return s.packetConn.Close()
+	}
+
+	return nil
+}
+
+// Func closeConns - close connections of all streaming sessions
+func (s *Server) closeConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+// Func bufferSize - configured buffer size or the default one
+func (s *Server) bufferSize() int {
+	if s.BufferSize <= 0 {
+		return DefaultBufferSize
+	}
+
+	return s.BufferSize
+}
+
+// Func serveStream - accept clients in a loop, respecting the connection limit
+func (s *Server) serveStream(listener net.Listener) error {
+	// Semaphore for the connection limit (nil means unlimited)
+	var slots chan struct{}
+	if s.MaxConns > 0 {
+		slots = make(chan struct{}, s.MaxConns)
+	}
+
+	for {
+		// Take a slot before accepting, so that extra clients wait in the backlog
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-s.quit:
+				return ErrServerClosed
+			}
+		}
+
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosing() {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		if !s.trackConn(conn) {
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+
+		go func() {
+			defer func() {
+				s.untrackConn(conn)
+				if slots != nil {
+					<-slots
+				}
+			}()
+
+			s.runSession(conn)
+		}()
+	}
+}
+
+// Func runSession - echo everything back until the connection is closed
+func (s *Server) runSession(conn net.Conn) {
+	buf := make([]byte, s.bufferSize())
+
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+
+		_, err = conn.Write(buf[:n])
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Func servePackets - echo every datagram back to its sender
+func (s *Server) servePackets(conn net.PacketConn) error {
+	// Register the loop, so that Shutdown waits for it
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.wg.Add(1)
+	s.mu.Unlock()
+	defer s.wg.Done()
+
+	buf := make([]byte, s.bufferSize())
+
+	for {
+		n, clientAddr, err := datagram.ReadFrom(conn, buf)
+		if errors.Is(err, datagram.ErrTruncated) {
+			if s.OnTruncated != nil {
+				s.OnTruncated(clientAddr)
+			}
+			continue
+		}
+		if err != nil {
+			if s.isClosing() {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		// A client that went away isn't a reason to stop serving others
+		_, _ = conn.WriteTo(buf[:n], clientAddr)
+	}
+}
+
+// Func isClosing - whether Shutdown or Close was called
+func (s *Server) isClosing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closing
+}
+
+// Func trackConn - register a session (false if the server is closing)
+func (s *Server) trackConn(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return false
+	}
+
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]struct{})
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+
+	return true
+}
+
+// Func untrackConn - close and unregister a finished session
+func (s *Server) untrackConn(conn net.Conn) {
+	_ = conn.Close()
+
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+
+	s.wg.Done()
+}
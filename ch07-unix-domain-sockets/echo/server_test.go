@@ -0,0 +1,307 @@
+package echo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// all transports served by Server
+var testNetworks = []string{"tcp", "udp", "unix", "unixgram", "unixpacket"}
+
+// testServerAddr returns an address to listen on for the network
+// (a socket file in dir for unix networks)
+func testServerAddr(network, dir string) string {
+	switch network {
+	case "tcp", "udp":
+		return "127.0.0.1:"
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("server%d.sock", os.Getpid()))
+}
+
+// testDial connects a client to the server
+// (unixgram clients need their own socket file to receive replies)
+func testDial(network string, addr net.Addr, dir string) (net.Conn, error) {
+	if network != "unixgram" {
+		return net.Dial(network, addr.String())
+	}
+
+	local := &net.UnixAddr{
+		Name: filepath.Join(dir, fmt.Sprintf("client%d.sock", os.Getpid())),
+		Net:  network,
+	}
+
+	return net.DialUnix(network, local, addr.(*net.UnixAddr))
+}
+
+// testStartServer starts the server and returns a channel with the Serve result
+func testStartServer(s *Server) (chan error, error) {
+	if err := s.Listen(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve()
+	}()
+
+	return done, nil
+}
+
+// testEcho writes the message and checks that it comes back
+func testEcho(conn net.Conn, msg []byte) error {
+	if _, err := conn.Write(msg); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(buf[:n], msg) {
+		return fmt.Errorf("expected reply %q; actual reply %q", msg, buf[:n])
+	}
+
+	return nil
+}
+
+func TestServerNetworks(t *testing.T) {
+	for _, network := range testNetworks {
+		t.Run(network, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "echo_server")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = os.RemoveAll(dir) }()
+
+			s := &Server{Network: network, Address: testServerAddr(network, dir)}
+			done, err := testStartServer(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client, err := testDial(network, s.Addr(), dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = client.Close() }()
+
+			// several round trips in a row
+			for i := 0; i < 3; i++ {
+				if err := testEcho(client, []byte("ping")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// shutdown closes the server, Serve reports it
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_ = s.Shutdown(ctx)
+
+			if err := <-done; !errors.Is(err, ErrServerClosed) {
+				t.Fatalf("expected ErrServerClosed; actual %v", err)
+			}
+		})
+	}
+}
+
+func TestServerUnknownNetwork(t *testing.T) {
+	s := &Server{Network: "ip", Address: "127.0.0.1"}
+	if err := s.Listen(); err == nil {
+		t.Fatal("expected unknown network error")
+	}
+}
+
+func TestServerMaxConns(t *testing.T) {
+	s := &Server{Network: "tcp", Address: "127.0.0.1:", MaxConns: 1}
+	done, err := testStartServer(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = s.Close()
+		<-done
+	}()
+
+	// the first client takes the only slot
+	first, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testEcho(first, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second one connects (backlog), but isn't served
+	second, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = second.Close() }()
+
+	_ = second.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	err = testEcho(second, []byte("second"))
+	var nErr net.Error
+	if !errors.As(err, &nErr) || !nErr.Timeout() {
+		t.Fatalf("expected timeout; actual %v", err)
+	}
+
+	// once the first client leaves, the second one gets its echo
+	_ = first.Close()
+
+	_ = second.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+	n, err := second.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := string(buf[:n]); actual != "second" {
+		t.Fatalf("expected reply %q; actual reply %q", "second", actual)
+	}
+}
+
+func TestServerShutdownDeadline(t *testing.T) {
+	s := &Server{Network: "tcp", Address: "127.0.0.1:"}
+	done, err := testStartServer(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an idle client keeps its session open
+	client, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if err := testEcho(client, []byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the session doesn't finish in time, so it's closed forcibly
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded; actual %v", err)
+	}
+
+	if err := <-done; !errors.Is(err, ErrServerClosed) {
+		t.Fatalf("expected ErrServerClosed; actual %v", err)
+	}
+
+	// the client sees the connection closed
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := client.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected EOF; actual %v", err)
+	}
+}
+
+func TestServerTruncated(t *testing.T) {
+	for _, network := range []string{"udp", "unixgram"} {
+		t.Run(network, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "echo_server")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = os.RemoveAll(dir) }()
+
+			truncated := make(chan net.Addr, 1)
+			s := &Server{
+				Network:     network,
+				Address:     testServerAddr(network, dir),
+				BufferSize:  16,
+				OnTruncated: func(from net.Addr) { truncated <- from },
+			}
+			done, err := testStartServer(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = s.Close()
+				<-done
+			}()
+
+			client, err := testDial(network, s.Addr(), dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = client.Close() }()
+
+			// larger than the maximum datagram size: reported, not echoed
+			if _, err := client.Write(bytes.Repeat([]byte("x"), 17)); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case from := <-truncated:
+				if from.String() != client.LocalAddr().String() {
+					t.Errorf("expected sender %q; actual %q", client.LocalAddr(), from)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("truncation wasn't reported")
+			}
+
+			// exactly the maximum size is fine
+			_ = client.SetReadDeadline(time.Now().Add(time.Second))
+			if err := testEcho(client, bytes.Repeat([]byte("y"), 16)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func BenchmarkServer(b *testing.B) {
+	msg := bytes.Repeat([]byte("x"), 512)
+
+	for _, network := range testNetworks {
+		b.Run(network, func(b *testing.B) {
+			dir, err := os.MkdirTemp("", "echo_bench")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer func() { _ = os.RemoveAll(dir) }()
+
+			s := &Server{Network: network, Address: testServerAddr(network, dir)}
+			done, err := testStartServer(s)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer func() {
+				_ = s.Close()
+				<-done
+			}()
+
+			client, err := testDial(network, s.Addr(), dir)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer func() { _ = client.Close() }()
+
+			buf := make([]byte, len(msg))
+			b.SetBytes(int64(len(msg)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := client.Write(msg); err != nil {
+					b.Fatal(err)
+				}
+
+				// streams may return the echo in several reads
+				if _, err := io.ReadFull(client, buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -12,63 +12,28 @@ func streamingEchoServer(
 	network string,
 	addr string,
 ) (net.Addr, error) {
+	return startServer(ctx, &Server{Network: network, Address: addr})
+}
+
+// start listening and serve asynchronously until the context is canceled
+func startServer(ctx context.Context, server *Server) (net.Addr, error) {
 	// start listening using given network and address
-	server, err := net.Listen(network, addr)
+	err := server.Listen()
 	if err != nil {
 		return nil, err
 	}
 
-	// do the rest asynchronously
+	// asynchronously wait for cancel and then close server
+	// to cancel any waiting operations on it
 	go func() {
-		// asynchronously wait for cancel and then close server
-		// to cancel any waiting operations on it
-		go func() {
-			<-ctx.Done()
-			_ = server.Close()
-		}()
+		<-ctx.Done()
+		_ = server.Close()
+	}()
 
-		// start accepting connections
-		acceptClients(server)
+	// do the rest asynchronously
+	go func() {
+		_ = server.Serve()
 	}()
 
 	return server.Addr(), nil
 }
-
-func acceptClients(server net.Listener) {
-	// accept new client
-	conn, err := server.Accept()
-	if err != nil {
-		return
-	}
-
-	// run session asynchronously
-	go runSession(conn)
-
-	// keep accepting new clients
-	acceptClients(server)
-}
-
-func runSession(conn net.Conn) {
-	// close connection at the end
-	defer func() { _ = conn.Close() }()
-
-	// start talking
-	talkEcho(conn)
-}
-
-func talkEcho(conn net.Conn) {
-	// read, answer with echo
-	buf := make([]byte, 1024)
-	n, err := conn.Read(buf)
-	if err != nil {
-		return
-	}
-
-	_, err = conn.Write(buf[:n])
-	if err != nil {
-		return
-	}
-
-	// keep talking
-	talkEcho(conn)
-}
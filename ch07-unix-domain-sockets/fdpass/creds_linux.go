@@ -0,0 +1,58 @@
+package fdpass
+
+import (
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Room for a credentials control message
+var credsSpace = unix.CmsgSpace(unix.SizeofUcred)
+
+// Func PassCreds - make the kernel attach the sender's credentials to every
+// message received on the connection, even if the sender doesn't send them
+// (must be enabled on the receiving side)
+func PassCreds(conn *net.UnixConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sErr error
+	err = raw.Control(func(fd uintptr) {
+		sErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_PASSCRED, 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sErr
+}
+
+// Func SendCreds - send the data with the files and the credentials of this
+// process attached; the kernel rejects credentials other than the real ones
+// (unless the process is privileged)
+func SendCreds(conn *net.UnixConn, data []byte, files ...*os.File) error {
+	creds := unix.UnixCredentials(&unix.Ucred{
+		Pid: int32(os.Getpid()),
+		Uid: uint32(os.Getuid()),
+		Gid: uint32(os.Getgid()),
+	})
+
+	return send(conn, data, files, creds)
+}
+
+// Func parseCreds - credentials from a control message, if it's SCM_CREDENTIALS
+func parseCreds(cmsg *unix.SocketControlMessage) (*Creds, bool) {
+	if cmsg.Header.Type != unix.SCM_CREDENTIALS {
+		return nil, false
+	}
+
+	ucred, err := unix.ParseUnixCredentials(cmsg)
+	if err != nil {
+		return nil, false
+	}
+
+	return &Creds{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid}, true
+}
@@ -0,0 +1,11 @@
+//go:build unix && !linux
+
+package fdpass
+
+import "golang.org/x/sys/unix"
+
+// No credentials control messages on this platform
+const credsSpace = 0
+
+// Func parseCreds - never credentials on this platform
+func parseCreds(_ *unix.SocketControlMessage) (*Creds, bool) { return nil, false }
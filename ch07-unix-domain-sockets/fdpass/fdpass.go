@@ -0,0 +1,176 @@
+//go:build unix
+
+// Package fdpass sends open files and process credentials over unix sockets
+// as ancillary data (SCM_RIGHTS, SCM_CREDENTIALS), e.g., so that a privileged
+// process can open files or listening sockets and hand them over to
+// unprivileged workers.
+//
+// The receiver gets new descriptors referring to the same open files:
+// the sender may close its copies right after sending.
+package fdpass
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrControlTruncated is returned if more files were sent than the receiver
+// expected, whether or not they fit into the receive buffer
+// (the received ones are closed)
+var ErrControlTruncated = errors.New("fdpass: control message truncated")
+
+// Message is data received along with files (and, optionally, credentials)
+type Message struct {
+	// Number of data bytes read into the buffer passed to Recv
+	N int
+	// Received files, the receiver is responsible for closing them
+	Files []*os.File
+	// Credentials of the sender if passed (Linux only), nil otherwise
+	Creds *Creds
+}
+
+// Creds are the process credentials passed with SCM_CREDENTIALS,
+// verified by the kernel
+type Creds struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// Func Send - send the data with the files attached; data must not be empty,
+// since ancillary data travels along with at least one byte
+func Send(conn *net.UnixConn, data []byte, files ...*os.File) error {
+	return send(conn, data, files, nil)
+}
+
+// Func send - write the data with SCM_RIGHTS and extra control messages
+func send(conn *net.UnixConn, data []byte, files []*os.File, extra []byte) error {
+	if len(data) == 0 {
+		return errors.New("fdpass: data is required")
+	}
+
+	var oob []byte
+	if len(files) > 0 {
+		fds := make([]int, len(files))
+		for i, f := range files {
+			fds[i] = int(f.Fd())
+		}
+		oob = unix.UnixRights(fds...)
+	}
+	oob = append(oob, extra...)
+
+	n, oobn, err := conn.WriteMsgUnix(data, oob, nil)
+	if err != nil {
+		return err
+	}
+
+	if n != len(data) || oobn != len(oob) {
+		return fmt.Errorf("fdpass: short write: %d/%d bytes, %d/%d control bytes", n, len(data), oobn, len(oob))
+	}
+
+	return nil
+}
+
+// Func Recv - read data into buf along with up to maxFiles files
+// and the sender's credentials, if any
+func Recv(conn *net.UnixConn, buf []byte, maxFiles int) (Message, error) {
+	oob := make([]byte, unix.CmsgSpace(4*max(maxFiles, 0))+credsSpace)
+
+	n, oobn, flags, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return Message{}, err
+	}
+
+	m := Message{N: n}
+
+	cmsgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return m, err
+	}
+
+	for _, cmsg := range cmsgs {
+		if cmsg.Header.Level != unix.SOL_SOCKET {
+			continue
+		}
+
+		switch cmsg.Header.Type {
+		case unix.SCM_RIGHTS:
+			fds, err := unix.ParseUnixRights(&cmsg)
+			if err != nil {
+				closeFiles(m.Files)
+				return Message{N: n}, err
+			}
+
+			for _, fd := range fds {
+				m.Files = append(m.Files, os.NewFile(uintptr(fd), fmt.Sprintf("fdpass-%d", fd)))
+			}
+		default:
+			if creds, ok := parseCreds(&cmsg); ok {
+				m.Creds = creds
+			}
+		}
+	}
+
+	// Control messages are aligned, so there may be room for extra files
+	if flags&unix.MSG_CTRUNC != 0 || len(m.Files) > maxFiles {
+		closeFiles(m.Files)
+		return Message{N: n}, ErrControlTruncated
+	}
+
+	return m, nil
+}
+
+// Func closeFiles
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
+
+// Func SendListener - hand over a TCP or unix listener, the name
+// (at least one byte) tells the receiver what it is
+func SendListener(conn *net.UnixConn, l net.Listener, name string) error {
+	filer, ok := l.(interface{ File() (*os.File, error) })
+	if !ok {
+		return fmt.Errorf("fdpass: can't get a file of %T", l)
+	}
+
+	// A duplicate of the listener's descriptor, the listener keeps working
+	f, err := filer.File()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	return Send(conn, []byte(name), f)
+}
+
+// Func RecvListener - receive a listener sent with SendListener and its name
+func RecvListener(conn *net.UnixConn) (net.Listener, string, error) {
+	buf := make([]byte, 256)
+
+	m, err := Recv(conn, buf, 1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(m.Files) != 1 {
+		closeFiles(m.Files)
+		return nil, "", fmt.Errorf("fdpass: expected 1 file; received %d", len(m.Files))
+	}
+
+	// FileListener duplicates the descriptor, so ours is closed
+	f := m.Files[0]
+	defer func() { _ = f.Close() }()
+
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return l, string(buf[:m.N]), nil
+}
@@ -0,0 +1,187 @@
+package fdpass
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// testPair - connected pair of unix stream sockets
+func testPair(t *testing.T) (*net.UnixConn, *net.UnixConn) {
+	t.Helper()
+
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conns := make([]*net.UnixConn, 2)
+	for i, fd := range fds {
+		f := os.NewFile(uintptr(fd), "socketpair")
+		conn, err := net.FileConn(f)
+		_ = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conns[i] = conn.(*net.UnixConn)
+		t.Cleanup(func() { _ = conn.Close() })
+	}
+
+	return conns[0], conns[1]
+}
+
+func TestSendFiles(t *testing.T) {
+	parent, worker := testPair(t)
+
+	// The "privileged" side opens the file and closes it right after sending
+	path := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(path, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Send(parent, []byte("secret.txt"), f); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	buf := make([]byte, 64)
+	m, err := Recv(worker, buf, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name := string(buf[:m.N]); name != "secret.txt" {
+		t.Errorf("expected %q; actual %q", "secret.txt", name)
+	}
+
+	if len(m.Files) != 1 {
+		t.Fatalf("expected 1 file; actual %d", len(m.Files))
+	}
+	defer func() { _ = m.Files[0].Close() }()
+
+	content, err := io.ReadAll(m.Files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "secret" {
+		t.Errorf("expected %q; actual %q", "secret", content)
+	}
+
+	if m.Creds != nil {
+		t.Errorf("expected no credentials; actual %+v", m.Creds)
+	}
+}
+
+func TestSendListener(t *testing.T) {
+	parent, worker := testPair(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The old process hands the listener over and stops accepting
+	if err := SendListener(parent, l, "http"); err != nil {
+		t.Fatal(err)
+	}
+	_ = l.Close()
+
+	received, name, err := RecvListener(worker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = received.Close() }()
+
+	if name != "http" {
+		t.Errorf("expected %q; actual %q", "http", name)
+	}
+
+	// The new process serves on the same address
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "hello from the worker")
+	})}
+	go func() { _ = srv.Serve(received) }()
+	defer func() { _ = srv.Close() }()
+
+	resp, err := http.Get("http://" + received.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "hello from the worker" {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+func TestSendCreds(t *testing.T) {
+	parent, worker := testPair(t)
+
+	if err := PassCreds(worker); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SendCreds(parent, []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Recv(worker, make([]byte, 8), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Creds{PID: int32(os.Getpid()), UID: uint32(os.Getuid()), GID: uint32(os.Getgid())}
+	if m.Creds == nil || *m.Creds != expected {
+		t.Errorf("expected %+v; actual %+v", expected, m.Creds)
+	}
+}
+
+func TestRecvTruncated(t *testing.T) {
+	parent, worker := testPair(t)
+
+	files := make([]*os.File, 8)
+	for i := range files {
+		f, err := os.Open(os.DevNull)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = f.Close() }()
+		files[i] = f
+	}
+
+	if err := Send(parent, []byte("x"), files...); err != nil {
+		t.Fatal(err)
+	}
+
+	// Room for a single file only
+	_, err := Recv(worker, make([]byte, 1), 1)
+	if !errors.Is(err, ErrControlTruncated) {
+		t.Fatalf("expected ErrControlTruncated; actual %v", err)
+	}
+}
+
+func TestSendEmpty(t *testing.T) {
+	parent, _ := testPair(t)
+
+	if err := Send(parent, nil); err == nil {
+		t.Fatal("expected error for empty data")
+	}
+}
@@ -0,0 +1,156 @@
+// Package unixsock manages the socket file of unix listeners: stale sockets
+// left by crashed servers are removed, the file gets the requested mode
+// and group, and it's removed when the listener is closed.
+package unixsock
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrInUse is returned if another server is listening on the socket
+var ErrInUse = errors.New("unixsock: socket is in use")
+
+// Time to wait for a server that might be listening on an existing socket
+const probeTimeout = time.Second
+
+// Options of the socket file, the zero value keeps the defaults
+// (mode according to the umask, the primary group of the process)
+type Options struct {
+	// Permissions of the socket file (only the write permission matters
+	// for connecting on Linux), 0 keeps the default
+	Mode fs.FileMode
+	// Name or id of the group owning the socket file, empty keeps the default
+	Group string
+}
+
+// Func ListenUnix - listen on a unix socket (network "unix" or "unixpacket").
+//
+// An existing socket file is removed if nobody accepts connections on it,
+// ErrInUse is returned otherwise; files other than sockets are never removed.
+// The mode and group are applied right after binding, so clients connecting
+// in between are queued until Accept; to close that window, put the socket
+// into a directory that only the intended clients can access.
+//
+// A name starting with "@" is a Linux abstract socket: it has no file,
+// so neither options nor cleanup apply to it.
+func ListenUnix(network, path string, opts Options) (*net.UnixListener, error) {
+	switch network {
+	case "unix", "unixpacket":
+	default:
+		return nil, net.UnknownNetworkError(network)
+	}
+
+	if strings.HasPrefix(path, "@") {
+		return listenAbstract(network, path, opts)
+	}
+
+	if err := removeStale(network, path); err != nil {
+		return nil, err
+	}
+
+	l, err := net.ListenUnix(network, &net.UnixAddr{Name: path, Net: network})
+	if err != nil {
+		return nil, err
+	}
+	l.SetUnlinkOnClose(true)
+
+	if err := applyOptions(path, opts); err != nil {
+		// Closing removes the file as well
+		_ = l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Func listenAbstract - listen on an abstract socket (Linux only)
+func listenAbstract(network, name string, opts Options) (*net.UnixListener, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("unixsock: abstract sockets aren't supported on %s", runtime.GOOS)
+	}
+
+	if opts != (Options{}) {
+		return nil, errors.New("unixsock: abstract sockets have no file for mode and group")
+	}
+
+	return net.ListenUnix(network, &net.UnixAddr{Name: name, Net: network})
+}
+
+// Func removeStale - remove the socket file if nobody listens on it
+func removeStale(network, path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("unixsock: %s exists and isn't a socket", path)
+	}
+
+	conn, err := net.DialTimeout(network, path, probeTimeout)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("%w: %s", ErrInUse, path)
+	}
+
+	// Only a refused connection means that nobody listens, other errors
+	// (e.g., permission denied or a timeout of a busy server) don't
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("unixsock: probing %s: %w", path, err)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// Func applyOptions - set the mode and the group of the socket file
+func applyOptions(path string, opts Options) error {
+	if opts.Mode != 0 {
+		if err := os.Chmod(path, opts.Mode); err != nil {
+			return err
+		}
+	}
+
+	if opts.Group != "" {
+		gid, err := lookupGroup(opts.Group)
+		if err != nil {
+			return err
+		}
+
+		if err := os.Chown(path, -1, gid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Func lookupGroup - group id by the name or the id itself
+func lookupGroup(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(g.Gid)
+}
@@ -0,0 +1,137 @@
+package unixsock
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestListenUnixStale(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "stale.sock")
+
+	// Simulate a crashed server: the socket file stays behind
+	crashed, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	crashed.SetUnlinkOnClose(false)
+	_ = crashed.Close()
+
+	if _, err := os.Lstat(socket); err != nil {
+		t.Fatalf("expected a stale socket file; actual %v", err)
+	}
+
+	l, err := ListenUnix("unix", socket, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+
+	// The socket file is removed on close
+	_ = l.Close()
+	if _, err := os.Lstat(socket); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the socket file removed; actual %v", err)
+	}
+}
+
+func TestListenUnixInUse(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "busy.sock")
+
+	l, err := ListenUnix("unix", socket, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+
+	if _, err := ListenUnix("unix", socket, Options{}); !errors.Is(err, ErrInUse) {
+		t.Errorf("expected ErrInUse; actual %v", err)
+	}
+
+	// The running server still has its socket
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+}
+
+func TestListenUnixNotSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regular")
+	if err := os.WriteFile(path, []byte("keep me"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ListenUnix("unix", path, Options{}); err == nil {
+		t.Fatal("expected an error for a regular file")
+	}
+
+	if data, err := os.ReadFile(path); err != nil || string(data) != "keep me" {
+		t.Errorf("expected the file kept; actual %q, %v", data, err)
+	}
+}
+
+func TestListenUnixOptions(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "perm.sock")
+
+	l, err := ListenUnix("unix", socket, Options{
+		Mode:  0o660,
+		Group: strconv.Itoa(os.Getgid()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+
+	info, err := os.Lstat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if perm := info.Mode().Perm(); perm != 0o660 {
+		t.Errorf("expected mode %v; actual %v", fs.FileMode(0o660), perm)
+	}
+
+	if _, err := ListenUnix("unix", filepath.Join(t.TempDir(), "nogroup.sock"), Options{
+		Group: "no-such-group-here",
+	}); err == nil {
+		t.Error("expected an error for an unknown group")
+	}
+}
+
+func TestListenUnixAbstract(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("abstract sockets are Linux only")
+	}
+
+	name := "@unixsock-test-" + strconv.Itoa(os.Getpid())
+
+	l, err := ListenUnix("unix", name, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+
+	if _, err := os.Lstat(name); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected no socket file; actual %v", err)
+	}
+
+	conn, err := net.Dial("unix", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+
+	if _, err := ListenUnix("unix", "@other", Options{Mode: 0o600}); err == nil {
+		t.Error("expected an error for options on an abstract socket")
+	}
+}
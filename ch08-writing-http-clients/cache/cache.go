@@ -0,0 +1,477 @@
+// Package cache is a private HTTP cache on the client side following
+// the basics of RFC 9111: Cache-Control max-age, no-store and no-cache,
+// Expires, revalidation with ETag and Last-Modified, and Vary.
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"learn-network-programming/ch08-writing-http-clients/transport"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default limit of the body size of the responses stored
+const defaultMaxBodySize = 10 << 20
+
+// Response header telling how the response was served:
+// "hit", "miss" or "revalidated" (Hit, Miss or Revalidated)
+const CacheHeader = "X-Cache"
+
+// Limit of the variants of a URL kept, the oldest are dropped beyond it
+const maxVariants = 16
+
+// Statuses cacheable by default (RFC 9110 section 15.1)
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// Transport serves GET requests from the store while the responses are
+// fresh, and revalidates stale ones having validators. Responses are stored
+// with their body read in full, so the first response isn't streamed.
+//
+// The latest response is stored under its URL; if it has Vary, it's also
+// stored under a key made of the URL and the request header values it
+// varies by, so that responses for other values don't overwrite it.
+type Transport struct {
+	// Transport to send requests with (http.DefaultTransport if not set)
+	Next  http.RoundTripper
+	Store Store
+	// Lookup counters (not counted if not set)
+	Metrics *Metrics
+	// Bodies longer than that aren't stored (10 MiB if not set)
+	MaxBodySize int64
+
+	// Clock, replaced in tests
+	now func() time.Time
+
+	// Serializes updates of the stored responses of a URL, so that
+	// concurrent ones don't lose each other's variants
+	locks keyedMutex
+}
+
+// Func Middleware - caching transport as a transport middleware
+func Middleware(store Store, metrics *Metrics) transport.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &Transport{Next: next, Store: store, Metrics: metrics}
+	}
+}
+
+// Func RoundTrip
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.URL.String()
+
+	if req.Method != http.MethodGet {
+		resp, err := t.next().RoundTrip(req)
+
+		// Unsafe methods invalidate the stored responses of the URL
+		if err == nil && unsafe(req.Method) && resp.StatusCode < 400 {
+			t.invalidate(key)
+		}
+
+		return resp, err
+	}
+
+	reqCC := parseCacheControl(req.Header)
+
+	// Bypass the cache: the caller forbids storing, or handles validation
+	// or ranges itself
+	if reqCC.has("no-store") || conditional(req) || req.Header.Get("Range") != "" {
+		t.Metrics.count(Miss)
+		return t.next().RoundTrip(req)
+	}
+
+	entry, ok := t.lookup(key, req)
+
+	if ok && t.fresh(entry, reqCC) {
+		t.Metrics.count(Hit)
+		return entry.response(req, Hit, age(entry, t.clock())), nil
+	}
+
+	// Ask the server to confirm the stored response is still valid
+	r := req
+	if ok && (entry.Header.Get("ETag") != "" || entry.Header.Get("Last-Modified") != "") {
+		r = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			r.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	requestTime := t.clock()
+	resp, err := t.next().RoundTrip(r)
+	if err != nil {
+		t.Metrics.count(Miss)
+		return nil, err
+	}
+	responseTime := t.clock()
+
+	if r != req && resp.StatusCode == http.StatusNotModified {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		entry = entry.updated(resp.Header, requestTime, responseTime)
+		t.save(key, entry)
+
+		t.Metrics.count(Revalidated)
+		return entry.response(req, Revalidated, age(entry, t.clock())), nil
+	}
+
+	t.Metrics.count(Miss)
+	return t.store(key, req, resp, requestTime, responseTime)
+}
+
+// Func store - keep the response if it's cacheable and return it
+// with the body that has been read
+func (t *Transport) store(
+	key string,
+	req *http.Request,
+	resp *http.Response,
+	requestTime, responseTime time.Time,
+) (*http.Response, error) {
+	resp.Header.Set(CacheHeader, Miss)
+
+	if !cacheable(resp) {
+		t.invalidate(key)
+		return resp, nil
+	}
+
+	maxBodySize := t.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+
+	// Too big to store: pass it on with the part read put back
+	if int64(len(body)) > maxBodySize {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+
+	header := resp.Header.Clone()
+	header.Del(CacheHeader)
+
+	t.save(key, &Entry{
+		StatusCode:   resp.StatusCode,
+		Header:       header,
+		Body:         body,
+		Vary:         varyValues(resp.Header, req.Header),
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+	})
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+// Func lookup - stored response of the URL matching the request:
+// the latest one, or the variant for the values of its Vary headers
+func (t *Transport) lookup(key string, req *http.Request) (*Entry, bool) {
+	entry, ok := t.Store.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	if varyMatches(entry, req) {
+		return entry, true
+	}
+
+	values := make(map[string]string, len(entry.Vary))
+	for name := range entry.Vary {
+		values[name] = strings.Join(req.Header.Values(name), ",")
+	}
+
+	variant, ok := t.Store.Get(variantKey(key, values))
+	if !ok || !varyMatches(variant, req) {
+		return nil, false
+	}
+
+	return variant, true
+}
+
+// Func save - store the entry as the latest response of the URL
+// and, if it varies, as the variant for its Vary values
+func (t *Transport) save(key string, entry *Entry) {
+	defer t.locks.lock(key)()
+
+	var variants []string
+	if latest, ok := t.Store.Get(key); ok {
+		variants = latest.Variants
+	}
+
+	latest := *entry
+	latest.Variants = nil
+
+	// The response doesn't vary (anymore): the variants are obsolete
+	if len(entry.Vary) == 0 {
+		for _, variant := range variants {
+			t.Store.Delete(variant)
+		}
+		t.Store.Set(key, &latest)
+		return
+	}
+
+	variant := variantKey(key, entry.Vary)
+	stored := latest
+	t.Store.Set(variant, &stored)
+
+	// Remember the variant (as the newest one) to invalidate it later
+	variants = slices.DeleteFunc(slices.Clone(variants), func(v string) bool { return v == variant })
+	variants = append(variants, variant)
+	for len(variants) > maxVariants {
+		t.Store.Delete(variants[0])
+		variants = variants[1:]
+	}
+
+	latest.Variants = variants
+	t.Store.Set(key, &latest)
+}
+
+// Func invalidate - delete the stored responses of the URL
+func (t *Transport) invalidate(key string) {
+	defer t.locks.lock(key)()
+
+	if latest, ok := t.Store.Get(key); ok {
+		for _, variant := range latest.Variants {
+			t.Store.Delete(variant)
+		}
+	}
+
+	t.Store.Delete(key)
+}
+
+// Func fresh - the stored response may be used without contacting the server
+func (t *Transport) fresh(e *Entry, reqCC directives) bool {
+	if reqCC.has("no-cache") || parseCacheControl(e.Header).has("no-cache") {
+		return false
+	}
+
+	lifetime, ok := freshnessLifetime(e)
+	if !ok {
+		return false
+	}
+
+	current := age(e, t.clock())
+
+	// The caller may accept only younger responses
+	if maxAge, ok := reqCC.seconds("max-age"); ok && current >= maxAge {
+		return false
+	}
+
+	return current < lifetime
+}
+
+// Func next - the transport to send requests with
+func (t *Transport) next() http.RoundTripper {
+	if t.Next == nil {
+		return http.DefaultTransport
+	}
+
+	return t.Next
+}
+
+// Func clock - current time
+func (t *Transport) clock() time.Time {
+	if t.now == nil {
+		return time.Now()
+	}
+
+	return t.now()
+}
+
+// Func cacheable - the response may be stored: the status is cacheable
+// by default, storing isn't forbidden, and it can be either served
+// fresh or revalidated
+func cacheable(resp *http.Response) bool {
+	if !cacheableStatus[resp.StatusCode] {
+		return false
+	}
+
+	cc := parseCacheControl(resp.Header)
+	if cc.has("no-store") || resp.Header.Get("Vary") == "*" {
+		return false
+	}
+
+	return cc.has("max-age") ||
+		resp.Header.Get("Expires") != "" ||
+		resp.Header.Get("ETag") != "" ||
+		resp.Header.Get("Last-Modified") != ""
+}
+
+// Func unsafe - the method may change the resource
+func unsafe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+
+	return true
+}
+
+// Func conditional - the request carries its own validators
+func conditional(req *http.Request) bool {
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range"} {
+		if req.Header.Get(name) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Func varyValues - request header values named by Vary of the response
+func varyValues(respHeader, reqHeader http.Header) map[string]string {
+	var values map[string]string
+
+	for _, line := range respHeader.Values("Vary") {
+		for _, name := range strings.Split(line, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+
+			if values == nil {
+				values = make(map[string]string)
+			}
+			values[name] = strings.Join(reqHeader.Values(name), ",")
+		}
+	}
+
+	return values
+}
+
+// Func variantKey - key of the variant of the URL for the Vary values
+func variantKey(key string, values map[string]string) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(key)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n%s: %s", name, values[name])
+	}
+
+	return b.String()
+}
+
+// Func varyMatches - the request has the same values of the Vary headers
+// as the one the response was stored for
+func varyMatches(e *Entry, req *http.Request) bool {
+	for name, value := range e.Vary {
+		if strings.Join(req.Header.Values(name), ",") != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Func updated - copy of the entry with the headers of a 304 response
+func (e *Entry) updated(header http.Header, requestTime, responseTime time.Time) *Entry {
+	u := *e
+	u.Header = e.Header.Clone()
+	u.RequestTime = requestTime
+	u.ResponseTime = responseTime
+
+	for name, values := range header {
+		// The stored body is unchanged
+		if name == "Content-Length" {
+			continue
+		}
+		u.Header[name] = values
+	}
+
+	return &u
+}
+
+// Func response - response to the request from the entry
+func (e *Entry) response(req *http.Request, result string, age time.Duration) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	header.Set(CacheHeader, result)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// struct keyedMutex - mutexes by key, created on demand
+// and dropped once nobody holds or waits for them
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+// struct keyLock
+type keyLock struct {
+	sync.Mutex
+	// Holders and waiters
+	refs int
+}
+
+// Func lock - lock the key, returns the function to unlock it
+func (k *keyedMutex) lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyLock)
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		k.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
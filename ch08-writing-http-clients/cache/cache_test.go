@@ -0,0 +1,395 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"learn-network-programming/ch08-writing-http-clients/transport"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// testClock - clock moved forward by the tests
+type testClock struct {
+	now time.Time
+}
+
+// Func Now
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// testCache - caching transport with a fake clock and inspectable metrics
+func testCache(store Store) (*Transport, *testClock, *prom.CounterVec) {
+	lookups := prom.NewCounterVec(prom.CounterOpts{Name: "lookups"}, []string{"result"})
+	clock := &testClock{now: time.Now()}
+
+	t := &Transport{
+		Store:   store,
+		Metrics: &Metrics{Lookups: prometheus.NewCounter(lookups)},
+		now:     clock.Now,
+	}
+
+	return t, clock, lookups
+}
+
+// testGet - GET the URL, returns the X-Cache header and the body
+func testGet(t *testing.T, c *http.Client, url string, header http.Header) (string, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.Header.Get(CacheHeader), string(b)
+}
+
+// testExpect - check the sequence of X-Cache results of GET requests
+func testExpect(t *testing.T, c *http.Client, url string, expected ...string) {
+	t.Helper()
+
+	for i, e := range expected {
+		if actual, _ := testGet(t, c, url, nil); actual != e {
+			t.Errorf("request %d: expected %s; actual %s", i+1, e, actual)
+		}
+	}
+}
+
+// testCount - lookups with the result
+func testCount(t *testing.T, lookups *prom.CounterVec, result string) float64 {
+	t.Helper()
+
+	var metric dto.Metric
+	if err := lookups.WithLabelValues(result).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+
+	return metric.GetCounter().GetValue()
+}
+
+// testServer - server with the response headers counting requests,
+// its Date follows the clock
+func testServer(t *testing.T, header http.Header, clock *testClock) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Date", clock.Now().UTC().Format(http.TimeFormat))
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+
+		if etag := header.Get("ETag"); etag != "" && r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		_, _ = io.WriteString(w, "content for "+r.Header.Get("Accept-Language"))
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, &requests
+}
+
+func TestMaxAge(t *testing.T) {
+	cache, clock, lookups := testCache(NewLRU(10))
+	ts, requests := testServer(t, http.Header{"Cache-Control": {"max-age=60"}}, clock)
+	c := &http.Client{Transport: cache}
+
+	testExpect(t, c, ts.URL, Miss, Hit, Hit)
+
+	// Stale without validators: fetched again
+	clock.now = clock.now.Add(61 * time.Second)
+	testExpect(t, c, ts.URL, Miss, Hit)
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected 2 requests; actual %d", n)
+	}
+	if hits, misses := testCount(t, lookups, Hit), testCount(t, lookups, Miss); hits != 3 || misses != 2 {
+		t.Errorf("expected 3 hits and 2 misses; actual %v and %v", hits, misses)
+	}
+
+	// The caller may refuse the cached response
+	if result, _ := testGet(t, c, ts.URL, http.Header{"Cache-Control": {"no-cache"}}); result != Miss {
+		t.Errorf("expected %s with no-cache; actual %s", Miss, result)
+	}
+	if result, _ := testGet(t, c, ts.URL, http.Header{"Cache-Control": {"no-store"}}); result != "" {
+		t.Errorf("expected the cache bypassed with no-store; actual %s", result)
+	}
+}
+
+func TestAge(t *testing.T) {
+	cache, clock, _ := testCache(NewLRU(10))
+	ts, _ := testServer(t, http.Header{"Cache-Control": {"max-age=60"}}, clock)
+	c := &http.Client{Transport: cache}
+
+	testGet(t, c, ts.URL, nil)
+	clock.now = clock.now.Add(30 * time.Second)
+
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	if a := resp.Header.Get("Age"); a != "30" && a != "31" {
+		t.Errorf("expected age 30; actual %q", a)
+	}
+}
+
+func TestNoStore(t *testing.T) {
+	cache, clock, _ := testCache(NewLRU(10))
+	ts, requests := testServer(t, http.Header{"Cache-Control": {"no-store, max-age=60"}}, clock)
+	c := &http.Client{Transport: cache}
+
+	testExpect(t, c, ts.URL, Miss, Miss)
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected 2 requests; actual %d", n)
+	}
+}
+
+func TestRevalidateETag(t *testing.T) {
+	cache, clock, lookups := testCache(NewLRU(10))
+	ts, requests := testServer(t, http.Header{"Cache-Control": {"no-cache"}, "Etag": {`"v1"`}}, clock)
+	c := &http.Client{Transport: cache}
+
+	testExpect(t, c, ts.URL, Miss, Revalidated, Revalidated)
+
+	// The body comes from the cache
+	if _, body := testGet(t, c, ts.URL, nil); body != "content for " {
+		t.Errorf("expected the stored body; actual %q", body)
+	}
+
+	if n := requests.Load(); n != 4 {
+		t.Errorf("expected 4 requests; actual %d", n)
+	}
+	if revalidated := testCount(t, lookups, Revalidated); revalidated != 3 {
+		t.Errorf("expected 3 revalidations; actual %v", revalidated)
+	}
+}
+
+func TestRevalidateLastModified(t *testing.T) {
+	// The static file server of ch9 sets Last-Modified and answers 304
+	ts := httptest.NewServer(http.FileServer(http.Dir("../files")))
+	defer ts.Close()
+
+	cache, _, _ := testCache(NewLRU(10))
+	c := &http.Client{Transport: cache}
+
+	testExpect(t, c, ts.URL+"/hello.txt", Miss, Revalidated)
+
+	_, body := testGet(t, c, ts.URL+"/hello.txt", nil)
+	if !strings.Contains(body, "ello") {
+		t.Errorf("expected hello.txt; actual %q", body)
+	}
+}
+
+func TestVary(t *testing.T) {
+	cache, clock, _ := testCache(NewLRU(10))
+	ts, requests := testServer(t, http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"Accept-Language"}}, clock)
+	c := &http.Client{Transport: cache}
+
+	for i, step := range []struct {
+		language string
+		result   string
+	}{
+		{"en", Miss},
+		{"en", Hit},
+		{"de", Miss},
+		{"de", Hit},
+		// the variants don't overwrite each other
+		{"en", Hit},
+		{"fr", Miss},
+		{"de", Hit},
+		{"en", Hit},
+	} {
+		result, body := testGet(t, c, ts.URL, http.Header{"Accept-Language": {step.language}})
+		if result != step.result || body != "content for "+step.language {
+			t.Errorf("request %d: expected %s %q; actual %s %q", i+1, step.result, step.language, result, body)
+		}
+	}
+
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests; actual %d", n)
+	}
+
+	// Unsafe methods invalidate all the variants
+	resp, err := c.Post(ts.URL, "text/plain", strings.NewReader("change"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	for _, language := range []string{"en", "de", "fr"} {
+		if result, _ := testGet(t, c, ts.URL, http.Header{"Accept-Language": {language}}); result != Miss {
+			t.Errorf("%s after POST: expected %s; actual %s", language, Miss, result)
+		}
+	}
+}
+
+func TestVaryLimit(t *testing.T) {
+	store := NewLRU(100)
+	cache, clock, _ := testCache(store)
+	ts, _ := testServer(t, http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"Accept-Language"}}, clock)
+	c := &http.Client{Transport: cache}
+
+	for i := 0; i < maxVariants+5; i++ {
+		testGet(t, c, ts.URL, http.Header{"Accept-Language": {fmt.Sprintf("lang-%d", i)}})
+	}
+
+	// The latest response and the newest variants are kept
+	if actual := store.Len(); actual != maxVariants+1 {
+		t.Errorf("expected %d entries; actual %d", maxVariants+1, actual)
+	}
+	if result, _ := testGet(t, c, ts.URL, http.Header{"Accept-Language": {"lang-0"}}); result != Miss {
+		t.Errorf("expected the oldest variant dropped; actual %s", result)
+	}
+}
+
+// slowStore - store taking its time to return entries (like a disk),
+// so that concurrent updates overlap
+type slowStore struct {
+	*LRU
+}
+
+// Func Get
+func (s slowStore) Get(key string) (*Entry, bool) {
+	entry, ok := s.LRU.Get(key)
+	time.Sleep(10 * time.Millisecond)
+
+	return entry, ok
+}
+
+func TestVaryConcurrent(t *testing.T) {
+	store := NewLRU(100)
+	cache, _, _ := testCache(slowStore{store})
+
+	// Responses for every variant arrive at once
+	var wg sync.WaitGroup
+	for i := 0; i < maxVariants; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.save("http://example.com/", &Entry{
+				StatusCode: http.StatusOK,
+				Vary:       map[string]string{"Accept-Language": fmt.Sprintf("lang-%d", i)},
+			})
+		}()
+	}
+	wg.Wait()
+
+	// None of the variants is lost, so all of them get invalidated
+	latest, ok := store.Get("http://example.com/")
+	if !ok {
+		t.Fatal("expected the latest response stored")
+	}
+	if actual := len(latest.Variants); actual != maxVariants {
+		t.Errorf("expected %d variants; actual %d", maxVariants, actual)
+	}
+
+	cache.invalidate("http://example.com/")
+	if actual := store.Len(); actual != 0 {
+		t.Errorf("expected no entries after invalidation; actual %d", actual)
+	}
+}
+
+func TestUnsafeInvalidates(t *testing.T) {
+	cache, clock, _ := testCache(NewLRU(10))
+	ts, _ := testServer(t, http.Header{"Cache-Control": {"max-age=60"}}, clock)
+	c := &http.Client{Transport: cache}
+
+	testExpect(t, c, ts.URL, Miss, Hit)
+
+	resp, err := c.Post(ts.URL, "text/plain", strings.NewReader("change"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	testExpect(t, c, ts.URL, Miss)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	big := strings.Repeat("x", 1000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, big)
+	}))
+	defer ts.Close()
+
+	store := NewLRU(10)
+	cache, _, _ := testCache(store)
+	cache.MaxBodySize = 100
+	c := &http.Client{Transport: cache}
+
+	for i := 0; i < 2; i++ {
+		result, body := testGet(t, c, ts.URL, nil)
+		if result != Miss || body != big {
+			t.Errorf("expected the full body not cached; actual %s with %d bytes", result, len(body))
+		}
+	}
+
+	if store.Len() != 0 {
+		t.Errorf("expected nothing stored; actual %d entries", store.Len())
+	}
+}
+
+func TestDiskStoreTransport(t *testing.T) {
+	ts, requests := testServer(t, http.Header{"Cache-Control": {"max-age=60"}}, &testClock{now: time.Now()})
+
+	dir := t.TempDir()
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, _, _ := testCache(store)
+	testExpect(t, &http.Client{Transport: cache}, ts.URL, Miss, Hit)
+
+	// A new store on the same directory keeps the responses
+	store, err = NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, _, _ = testCache(store)
+	testExpect(t, &http.Client{Transport: cache}, ts.URL, Hit)
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected 1 request; actual %d", n)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	ts, _ := testServer(t, http.Header{"Cache-Control": {"max-age=60"}}, &testClock{now: time.Now()})
+
+	// Metrics are optional
+	c := transport.NewBuilder(ts.Client().Transport).
+		Use(transport.RequestID(), Middleware(NewLRU(1), nil)).
+		Client(time.Second)
+
+	testExpect(t, c, ts.URL, Miss, Hit)
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// directives of Cache-Control by lowercase name
+type directives map[string]string
+
+// Func parseCacheControl - directives of all Cache-Control headers
+func parseCacheControl(header http.Header) directives {
+	d := make(directives)
+
+	for _, line := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(line, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+
+			d[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return d
+}
+
+// Func has - whether the directive is present
+func (d directives) has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+// Func seconds - value of a delta-seconds directive (e.g., max-age)
+func (d directives) seconds(name string) (time.Duration, bool) {
+	value, ok := d[name]
+	if !ok {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		// Invalid values make the response stale
+		return 0, true
+	}
+
+	return time.Duration(n) * time.Second, true
+}
+
+// Func freshnessLifetime - how long the response is fresh after it was
+// generated: max-age, or Expires minus Date; false if not specified
+// (heuristic freshness isn't used, such responses are revalidated)
+func freshnessLifetime(e *Entry) (time.Duration, bool) {
+	if maxAge, ok := parseCacheControl(e.Header).seconds("max-age"); ok {
+		return maxAge, true
+	}
+
+	expires := e.Header.Get("Expires")
+	if expires == "" {
+		return 0, false
+	}
+
+	expiresAt, err := http.ParseTime(expires)
+	if err != nil {
+		// Invalid dates (like "0") mean already expired
+		return 0, true
+	}
+
+	date, err := http.ParseTime(e.Header.Get("Date"))
+	if err != nil {
+		date = e.ResponseTime
+	}
+
+	return expiresAt.Sub(date), true
+}
+
+// Func age - current age of the response (RFC 9111 section 4.2.3)
+func age(e *Entry, now time.Time) time.Duration {
+	var apparentAge time.Duration
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		apparentAge = max(e.ResponseTime.Sub(date), 0)
+	}
+
+	var ageValue time.Duration
+	if seconds, err := strconv.ParseInt(e.Header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+
+	responseDelay := e.ResponseTime.Sub(e.RequestTime)
+	correctedAge := ageValue + responseDelay
+
+	return max(apparentAge, correctedAge) + now.Sub(e.ResponseTime)
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// DiskStore keeps entries in files of a directory, surviving restarts;
+// it isn't limited in size, remove the directory to clear it
+type DiskStore struct {
+	dir string
+}
+
+// Func NewDiskStore - store in the directory, created if needed
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &DiskStore{dir: dir}, nil
+}
+
+// Func path - file of the key
+func (d *DiskStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:]))
+}
+
+// Func Get - unreadable entries are misses
+func (d *DiskStore) Get(key string) (*Entry, bool) {
+	f, err := os.Open(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	defer func() { _ = f.Close() }()
+
+	entry := new(Entry)
+	if err := gob.NewDecoder(f).Decode(entry); err != nil {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// Func Set - write to a temporary file and rename it, so that readers
+// never see partial entries; failures leave the key uncached
+func (d *DiskStore) Set(key string, entry *Entry) {
+	f, err := os.CreateTemp(d.dir, "tmp-*")
+	if err != nil {
+		return
+	}
+
+	err = gob.NewEncoder(f).Encode(entry)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), d.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+}
+
+// Func Delete
+func (d *DiskStore) Delete(key string) {
+	_ = os.Remove(d.path(key))
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Results of cache lookups
+const (
+	// Served from the cache without contacting the server
+	Hit = "hit"
+	// Sent to the server: not cached, stale without validators, or not cacheable
+	Miss = "miss"
+	// Stale, the server confirmed it's still valid (304)
+	Revalidated = "revalidated"
+)
+
+// Metrics of the cache
+type Metrics struct {
+	// Counter of lookups labeled by "result": Hit, Miss or Revalidated
+	Lookups metrics.Counter
+}
+
+// Func NewMetrics - metrics registered in the default prometheus registry
+// (once per namespace and subsystem)
+func NewMetrics(namespace, subsystem string) *Metrics {
+	return &Metrics{
+		Lookups: prometheus.NewCounterFrom(
+			prom.CounterOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "cache_lookup_count",
+				Help:      "Total HTTP cache lookups by result",
+			},
+			[]string{"result"},
+		),
+	}
+}
+
+// Func count - count the lookup result
+func (m *Metrics) count(result string) {
+	if m != nil {
+		m.Lookups.With("result", result).Add(1)
+	}
+}
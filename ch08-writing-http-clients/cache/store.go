@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Entry is a stored response
+type Entry struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Values of the request headers named by Vary
+	Vary map[string]string
+	// Keys of the variants stored for other Vary values, oldest first
+	// (set in the entry stored under the URL only)
+	Variants []string
+	// When the request was sent and the response received,
+	// to calculate the age of the response
+	RequestTime  time.Time
+	ResponseTime time.Time
+}
+
+// Store keeps entries by key, it must be safe for concurrent use
+type Store interface {
+	Get(key string) (*Entry, bool)
+	Set(key string, entry *Entry)
+	Delete(key string)
+}
+
+// LRU is an in-memory store evicting the least recently used entries
+type LRU struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+// lruItem is an element of the recency list
+type lruItem struct {
+	key   string
+	entry *Entry
+}
+
+// Func NewLRU - store of up to maxEntries entries
+func NewLRU(maxEntries int) *LRU {
+	return &LRU{
+		maxEntries: max(maxEntries, 1),
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Func Get
+func (l *LRU) Get(key string) (*Entry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(e)
+
+	return e.Value.(*lruItem).entry, true
+}
+
+// Func Set
+func (l *LRU) Set(key string, entry *Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.entries[key]; ok {
+		e.Value.(*lruItem).entry = entry
+		l.order.MoveToFront(e)
+		return
+	}
+
+	l.entries[key] = l.order.PushFront(&lruItem{key: key, entry: entry})
+
+	for l.order.Len() > l.maxEntries {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruItem).key)
+	}
+}
+
+// Func Delete
+func (l *LRU) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.entries[key]; ok {
+		l.order.Remove(e)
+		delete(l.entries, key)
+	}
+}
+
+// Func Len - number of entries
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.order.Len()
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	l := NewLRU(2)
+
+	l.Set("a", &Entry{StatusCode: 1})
+	l.Set("b", &Entry{StatusCode: 2})
+
+	// Using a makes b the least recently used
+	if _, ok := l.Get("a"); !ok {
+		t.Fatal("expected a")
+	}
+	l.Set("c", &Entry{StatusCode: 3})
+
+	if _, ok := l.Get("b"); ok {
+		t.Error("expected b evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := l.Get(key); !ok {
+			t.Errorf("expected %s kept", key)
+		}
+	}
+
+	l.Delete("a")
+	if _, ok := l.Get("a"); ok || l.Len() != 1 {
+		t.Errorf("expected a deleted; actual %d entries", l.Len())
+	}
+}
+
+func TestDiskStore(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := &Entry{
+		StatusCode:   http.StatusOK,
+		Header:       http.Header{"Etag": {`"v1"`}},
+		Body:         []byte("body"),
+		Vary:         map[string]string{"Accept-Language": "en"},
+		ResponseTime: time.Now().Round(0),
+	}
+	d.Set("http://example.com/", entry)
+
+	actual, ok := d.Get("http://example.com/")
+	if !ok {
+		t.Fatal("expected the entry")
+	}
+	if string(actual.Body) != "body" || actual.Header.Get("ETag") != `"v1"` ||
+		actual.Vary["Accept-Language"] != "en" || !actual.ResponseTime.Equal(entry.ResponseTime) {
+		t.Errorf("expected %+v; actual %+v", entry, actual)
+	}
+
+	// Corrupted files are misses
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file; actual %v", files)
+	}
+	if err := os.WriteFile(files[0], []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.Get("http://example.com/"); ok {
+		t.Error("expected a miss for a corrupted entry")
+	}
+
+	d.Delete("http://example.com/")
+	if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+		t.Errorf("expected the file removed; actual %v", err)
+	}
+}
+
+func TestFreshnessLifetime(t *testing.T) {
+	date := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for name, test := range map[string]struct {
+		header   http.Header
+		lifetime time.Duration
+		ok       bool
+	}{
+		"max-age":         {http.Header{"Cache-Control": {"public, max-age=60"}}, time.Minute, true},
+		"max-age wins":    {http.Header{"Cache-Control": {"max-age=10"}, "Expires": {"Mon, 01 Jan 2024 13:00:00 GMT"}}, 10 * time.Second, true},
+		"expires":         {http.Header{"Date": {"Mon, 01 Jan 2024 12:00:00 GMT"}, "Expires": {"Mon, 01 Jan 2024 13:00:00 GMT"}}, time.Hour, true},
+		"invalid expires": {http.Header{"Expires": {"0"}}, 0, true},
+		"invalid max-age": {http.Header{"Cache-Control": {"max-age=soon"}}, 0, true},
+		"nothing":         {http.Header{"Etag": {`"v1"`}}, 0, false},
+	} {
+		lifetime, ok := freshnessLifetime(&Entry{Header: test.header, ResponseTime: date})
+		if lifetime != test.lifetime || ok != test.ok {
+			t.Errorf("%s: expected %s, %v; actual %s, %v", name, test.lifetime, test.ok, lifetime, ok)
+		}
+	}
+}
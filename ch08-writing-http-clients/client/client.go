@@ -0,0 +1,117 @@
+// Package client wraps http.Client with per-attempt and overall timeouts,
+// retries of idempotent requests, and automatic draining of response bodies.
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Defaults for the retrying client
+const (
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 100 * time.Millisecond
+	defaultMaxDelay    = 5 * time.Second
+)
+
+// Limit of the bytes read while draining a body, a connection with
+// a longer body left is closed instead of being reused
+const maxDrain = 64 << 10
+
+// Client retries failed requests with exponential backoff and full jitter.
+// Only idempotent requests are retried: by method (GET, HEAD, OPTIONS,
+// TRACE, PUT, DELETE) or with an Idempotency-Key header, and only
+// if their body can be sent again (http.Request.GetBody).
+//
+// The overall deadline comes from the request context (and Timeout if set),
+// the per-attempt one from AttemptTimeout.
+type Client struct {
+	// Client to send the attempts with (http.DefaultClient if not set)
+	HTTPClient *http.Client
+	// Maximum number of attempts (3 if not set)
+	MaxAttempts int
+	// Timeout of a single attempt including reading the body (no timeout if not set)
+	AttemptTimeout time.Duration
+	// Timeout of all attempts together (no timeout if not set)
+	Timeout time.Duration
+	// Backoff before the second attempt, doubled every time (100 ms if not set)
+	BaseDelay time.Duration
+	// Upper limit of the backoff (5 s if not set); a longer Retry-After
+	// isn't waited for, the response is returned instead
+	MaxDelay time.Duration
+}
+
+// Func Do - send the request, retrying on network errors and on 408, 429,
+// 502, 503 and 504 responses, and pass the final response to handle
+// (may be nil). The body is drained and closed after handle returns,
+// so handle must not keep it. Returns the error of handle.
+func (c *Client) Do(req *http.Request, handle func(*http.Response) error) error {
+	// Apply the overall timeout
+	if c.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), c.Timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
+	policy := Policy{MaxAttempts: c.MaxAttempts, BaseDelay: c.BaseDelay, MaxDelay: c.MaxDelay}
+
+	resp, err := policy.Do(req, c.attempt)
+	if err != nil {
+		return err
+	}
+	defer DrainAndClose(resp.Body)
+
+	if handle == nil {
+		return nil
+	}
+
+	return handle(resp)
+}
+
+// Func attempt - send the request within its own timeout, which is
+// released once the body is closed
+func (c *Client) attempt(req *http.Request) (*http.Response, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	if c.AttemptTimeout <= 0 {
+		return httpClient.Do(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), c.AttemptTimeout)
+
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// cancelBody releases the timeout of its attempt on Close
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Func Close
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
+
+// Func DrainAndClose - read the rest of the body (up to 64 KiB) so that
+// the connection can be reused, and close it
+func DrainAndClose(body io.ReadCloser) {
+	_, _ = io.CopyN(io.Discard, body, maxDrain)
+	_ = body.Close()
+}
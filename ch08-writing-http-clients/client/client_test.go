@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClient - client with short delays for tests
+func testClient() *Client {
+	return &Client{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    50 * time.Millisecond,
+	}
+}
+
+// testServer - server failing with the status the first failures requests
+func testServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			http.Error(w, "try later", status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, &requests
+}
+
+type result struct {
+	OK bool `json:"ok"`
+}
+
+func TestRetryStatus(t *testing.T) {
+	ts, requests := testServer(t, 2, http.StatusServiceUnavailable, http.Header{"Retry-After": {"0"}})
+
+	r, err := GetJSON[result](context.Background(), testClient(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !r.OK || requests.Load() != 3 {
+		t.Errorf("expected success after 3 requests; actual %v after %d", r.OK, requests.Load())
+	}
+}
+
+func TestRetryAfterTooLong(t *testing.T) {
+	ts, requests := testServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"3600"}})
+
+	_, err := GetJSON[result](context.Background(), testClient(), ts.URL)
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected status error 429; actual %v", err)
+	}
+	if statusErr.Body != "try later" {
+		t.Errorf("expected body %q; actual %q", "try later", statusErr.Body)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 request; actual %d", requests.Load())
+	}
+}
+
+func TestRetryIdempotency(t *testing.T) {
+	ts, requests := testServer(t, 1, http.StatusBadGateway, nil)
+
+	// POST isn't retried
+	_, err := PostJSON[result](context.Background(), testClient(), ts.URL, map[string]int{"n": 1})
+	if err == nil || requests.Load() != 1 {
+		t.Fatalf("expected a single failed request; actual %v after %d", err, requests.Load())
+	}
+
+	// unless it has an idempotency key
+	ts, requests = testServer(t, 1, http.StatusBadGateway, nil)
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"n":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Idempotency-Key", "42")
+
+	r, err := DoJSON[result](testClient(), req)
+	if err != nil || !r.OK || requests.Load() != 2 {
+		t.Errorf("expected success after 2 requests; actual %v, %v after %d", r.OK, err, requests.Load())
+	}
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	// The first request hangs until the client gives up on it
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer ts.Close()
+
+	c := testClient()
+	c.AttemptTimeout = 100 * time.Millisecond
+
+	r, err := GetJSON[result](context.Background(), c, ts.URL)
+	if err != nil || !r.OK {
+		t.Fatalf("expected success; actual %v, %v", r.OK, err)
+	}
+
+	// The overall timeout stops retrying a server that always hangs
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer hanging.Close()
+
+	c.MaxAttempts = 10
+	c.Timeout = 150 * time.Millisecond
+
+	start := time.Now()
+	_, err = GetJSON[result](context.Background(), c, hanging.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded; actual %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to give up after %s; actual %s", c.Timeout, elapsed)
+	}
+}
+
+func TestBodyDrained(t *testing.T) {
+	// Count connections: drained bodies let the client reuse one
+	var conns atomic.Int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("unread ", 1000)))
+	}))
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	c := &Client{HTTPClient: ts.Client()}
+	for i := 0; i < 5; i++ {
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Nobody reads the body
+		if err := c.Do(req, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := conns.Load(); n != 1 {
+		t.Errorf("expected 1 connection; actual %d", n)
+	}
+}
+
+func TestPostJSON(t *testing.T) {
+	type user struct {
+		First string
+		Last  string
+		ID    int
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var u user
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		u.ID = 1
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(u)
+	}))
+	defer ts.Close()
+
+	u, err := PostJSON[user](context.Background(), testClient(), ts.URL, user{First: "Dima", Last: "Kochetov"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := (user{"Dima", "Kochetov", 1}); u != expected {
+		t.Errorf("expected %v; actual %v", expected, u)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for value, expected := range map[string]time.Duration{
+		"120":                           2 * time.Minute,
+		"Mon, 01 Jan 2024 12:00:30 GMT": 30 * time.Second,
+		"Mon, 01 Jan 2024 11:00:00 GMT": 0,
+	} {
+		actual, ok := RetryAfter(http.Header{"Retry-After": {value}}, now)
+		if !ok || actual != expected {
+			t.Errorf("%q: expected %s; actual %s, %v", value, expected, actual, ok)
+		}
+	}
+
+	for _, value := range []string{"", "-1", "soon"} {
+		if _, ok := RetryAfter(http.Header{"Retry-After": {value}}, now); ok {
+			t.Errorf("%q: expected no delay", value)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Limit of the response body kept in StatusError
+const maxErrorBody = 1 << 10
+
+// StatusError is a response with a non-2xx status
+type StatusError struct {
+	StatusCode int
+	Status     string
+	// Beginning of the response body
+	Body string
+}
+
+// Func Error
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return "unexpected status " + e.Status
+	}
+
+	return fmt.Sprintf("unexpected status %s: %s", e.Status, e.Body)
+}
+
+// Func Decode - decode the JSON body of a 2xx response into T,
+// an empty body leaves the zero value; other statuses are a *StatusError
+func Decode[T any](resp *http.Response) (T, error) {
+	var v T
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return v, &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+
+	err := json.NewDecoder(resp.Body).Decode(&v)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return v, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return v, nil
+}
+
+// Func DoJSON - send the request with the client and decode the response into T
+func DoJSON[T any](c *Client, req *http.Request) (T, error) {
+	req.Header.Set("Accept", "application/json")
+
+	var v T
+	err := c.Do(req, func(resp *http.Response) error {
+		var err error
+		v, err = Decode[T](resp)
+		return err
+	})
+
+	return v, err
+}
+
+// Func GetJSON - GET the URL and decode the response into T
+func GetJSON[T any](ctx context.Context, c *Client, url string) (T, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		var v T
+		return v, err
+	}
+
+	return DoJSON[T](c, req)
+}
+
+// Func PostJSON - POST in encoded as JSON to the URL and decode the response
+// into T; set an Idempotency-Key header with DoJSON to have it retried
+func PostJSON[T any](ctx context.Context, c *Client, url string, in any) (T, error) {
+	var v T
+
+	body, err := json.Marshal(in)
+	if err != nil {
+		return v, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return v, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return DoJSON[T](c, req)
+}
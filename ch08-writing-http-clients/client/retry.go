@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy of retries shared by Client and the transport.Retry middleware:
+// idempotent requests with replayable bodies are retried on network
+// errors and on 408, 429, 502, 503 and 504 responses
+type Policy struct {
+	// Maximum number of attempts (3 if not set)
+	MaxAttempts int
+	// Backoff before the second attempt, doubled every time (100 ms if not set)
+	BaseDelay time.Duration
+	// Upper limit of the backoff (5 s if not set); a longer Retry-After
+	// isn't waited for, the response is returned instead
+	MaxDelay time.Duration
+}
+
+// Func Do - send the request with send until it succeeds, the response
+// isn't worth retrying or attempts run out. The request of every attempt
+// after the first one is a copy with the body recreated. Responses retried
+// are drained and closed, the final one is returned with its body open.
+//
+// The overall deadline comes from the request context.
+func (p Policy) Do(
+	req *http.Request,
+	send func(*http.Request) (*http.Response, error),
+) (*http.Response, error) {
+	ctx := req.Context()
+
+	// Requests that aren't safe to repeat get a single attempt
+	maxAttempts := 1
+	if Replayable(req) {
+		maxAttempts = p.MaxAttempts
+		if maxAttempts <= 0 {
+			maxAttempts = defaultMaxAttempts
+		}
+	}
+
+	var (
+		errs  []error
+		delay time.Duration
+	)
+	for attempt := 0; ; attempt++ {
+		// Wait before retrying (or give up if the context is done)
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, errors.Join(append(errs, ctx.Err())...)
+			case <-time.After(delay):
+			}
+		}
+
+		r := req
+		// The body of the previous attempt has been consumed
+		if attempt > 0 {
+			r = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, errors.Join(append(errs, err)...)
+				}
+				r.Body = body
+			}
+		}
+
+		last := attempt == maxAttempts-1
+
+		resp, err := send(r)
+		if err != nil {
+			// The caller gave up or it was the last attempt
+			if ctx.Err() != nil || last {
+				return nil, errors.Join(append(errs, err)...)
+			}
+
+			errs = append(errs, fmt.Errorf("attempt %d: %w", attempt+1, err))
+			delay = p.backoff(attempt + 1)
+			continue
+		}
+
+		// Retry if the server asks for it in time, otherwise return the response
+		if last || !RetryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		wait, ok := p.retryDelay(ctx, resp, attempt+1)
+		if !ok {
+			return resp, nil
+		}
+		DrainAndClose(resp.Body)
+
+		errs = append(errs, fmt.Errorf("attempt %d: %s", attempt+1, resp.Status))
+		delay = wait
+	}
+}
+
+// Func Replayable - whether the request may be sent again:
+// it's idempotent and its body (if any) can be recreated
+func Replayable(req *http.Request) bool {
+	if !idempotent(req) {
+		return false
+	}
+
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// Func idempotent - idempotent method or an idempotency key
+// (the same rule http.Transport uses)
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	_, hasKey := req.Header["Idempotency-Key"]
+	_, hasXKey := req.Header["X-Idempotency-Key"]
+
+	return hasKey || hasXKey
+}
+
+// Func RetryableStatus - the response status is worth retrying
+func RetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// Func RetryAfter - delay requested with Retry-After,
+// either in seconds or as an HTTP date
+func RetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(date.Sub(now), 0), true
+}
+
+// Func retryDelay - time to wait before the next attempt: the backoff
+// or Retry-After if longer; false if it's beyond MaxDelay or the deadline
+func (p Policy) retryDelay(ctx context.Context, resp *http.Response, attempt int) (time.Duration, bool) {
+	delay := p.backoff(attempt)
+
+	if after, ok := RetryAfter(resp.Header, time.Now()); ok {
+		if after > p.maxDelay() {
+			return 0, false
+		}
+		delay = max(delay, after)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+// Func backoff - random delay before the given attempt
+func (p Policy) backoff(attempt int) time.Duration {
+	base := p.BaseDelay
+	if base <= 0 {
+		base = defaultBaseDelay
+	}
+
+	return Backoff(attempt, base, p.maxDelay())
+}
+
+// Func maxDelay - MaxDelay or its default
+func (p Policy) maxDelay() time.Duration {
+	if p.MaxDelay <= 0 {
+		return defaultMaxDelay
+	}
+
+	return p.MaxDelay
+}
+
+// Func Backoff - random delay before the given attempt:
+// uniformly distributed in [0, min(maxDelay, base * 2^(attempt-1))]
+func Backoff(attempt int, base, maxDelay time.Duration) time.Duration {
+	// Double the delay, stopping at the limit (also prevents overflows)
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxDelay)
+
+	// Full jitter spreads retries of many clients over time
+	return rand.N(delay + 1)
+}
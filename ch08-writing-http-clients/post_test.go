@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"learn-network-programming/ch08-writing-http-clients/client"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
@@ -94,6 +96,37 @@ func TestPostUser(t *testing.T) {
 	}
 }
 
+// Func TestPostUserClient - the same flow with the resilient client,
+// which drains and closes the bodies itself
+func TestPostUserClient(t *testing.T) {
+	// Create a test server with the only handler
+	ts := httptest.NewServer(http.HandlerFunc(handlePostUser))
+	// Close at scope exit
+	defer func() {
+		ts.Close()
+	}()
+
+	// Create a client with per-attempt and overall timeouts
+	c := &client.Client{
+		AttemptTimeout: time.Second,
+		Timeout:        5 * time.Second,
+	}
+
+	// GET is rejected: the status is reported as an error
+	_, err := client.GetJSON[User](context.Background(), c, ts.URL)
+	var statusErr *client.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status %d; actual %v", http.StatusMethodNotAllowed, err)
+	}
+
+	// POST the user, the accepted response has no body
+	u := User{First: "Dima", Last: "Kochetov"}
+	_, err = client.PostJSON[struct{}](context.Background(), c, ts.URL, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 // Func TestMultipartPost
 func TestMultipartPost(t *testing.T) {
 	// Creat a new buffer for filling with request data
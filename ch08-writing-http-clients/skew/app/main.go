@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"learn-network-programming/ch08-writing-http-clients/skew"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+)
+
+// Servers queried if none are given
+var defaultURLs = []string{
+	"https://time.gov/",
+	"https://www.google.com/",
+	"https://www.cloudflare.com/",
+}
+
+var timeout = flag.Duration("t", 5*time.Second, "timeout of a single request")
+
+// Func init
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(
+			flag.CommandLine.Output(),
+			"Usage:\n\t%s [flags] [URL...]\n",
+			filepath.Base(os.Args[0]),
+		)
+		flag.PrintDefaults()
+	}
+}
+
+// Func main
+func main() {
+	flag.Parse()
+
+	urls := flag.Args()
+	if len(urls) == 0 {
+		urls = defaultURLs
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c := skew.Checker{Timeout: *timeout}
+	samples := c.MeasureAll(ctx, urls)
+
+	for _, s := range samples {
+		if s.Err != nil {
+			fmt.Printf("%s: %v\n", s.URL, s.Err)
+			continue
+		}
+
+		fmt.Printf(
+			"%s: %s (rtt %s)\n",
+			s.URL,
+			round(skew.Estimate{Offset: s.Offset, Error: s.Error}),
+			s.RTT.Round(time.Millisecond),
+		)
+	}
+
+	estimate, err := skew.Combine(samples)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("offset: %s (%d servers)\n", round(estimate), estimate.Samples)
+}
+
+// Func round - estimate rounded to milliseconds for printing
+func round(e skew.Estimate) skew.Estimate {
+	e.Offset = e.Offset.Round(time.Millisecond)
+	e.Error = e.Error.Round(time.Millisecond)
+
+	return e
+}
@@ -0,0 +1,181 @@
+// Package skew estimates the offset of the local clock from HTTP Date headers.
+//
+// A Date header has a resolution of one second and is generated at some
+// point between sending the request (t0) and receiving the response (t1),
+// so the server clock minus the local one lies within
+// [Date - t1, Date + 1s - t0]. The estimate is the midpoint of that range:
+// Date + 0.5s - (t0 + t1) / 2, with the error bound of (RTT + 1s) / 2.
+// Combining several servers narrows the range to the intersection.
+package skew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Default timeout of a single request
+const defaultTimeout = 5 * time.Second
+
+// Resolution of the Date header
+const dateResolution = time.Second
+
+var (
+	// ErrNoDate is returned for responses without a valid Date header
+	ErrNoDate = errors.New("no valid Date header")
+	// ErrInconsistent is returned if the servers' ranges don't overlap,
+	// i.e., at least one of their clocks is off
+	ErrInconsistent = errors.New("servers disagree on the time")
+)
+
+// Sample is the offset measured against one server
+type Sample struct {
+	URL string
+	// Server clock minus the local clock (positive if the local one is behind)
+	Offset time.Duration
+	// The real offset is within Offset ± Error
+	Error time.Duration
+	RTT   time.Duration
+	// Why the server couldn't be measured, the other fields are zero then
+	Err error
+}
+
+// Func Range - lowest and highest possible offset
+func (s Sample) Range() (time.Duration, time.Duration) {
+	return s.Offset - s.Error, s.Offset + s.Error
+}
+
+// Estimate is the offset combined from several samples
+type Estimate struct {
+	Offset time.Duration
+	Error  time.Duration
+	// Number of samples combined
+	Samples int
+}
+
+// Func String - offset with its error bound, e.g., "+1.5s ± 600ms"
+func (e Estimate) String() string {
+	sign := "+"
+	if e.Offset < 0 {
+		sign = "-"
+	}
+
+	return fmt.Sprintf("%s%s ± %s", sign, e.Offset.Abs(), e.Error)
+}
+
+// Checker measures offsets with HEAD requests
+type Checker struct {
+	// Client to send the requests with (http.DefaultClient if not set)
+	Client *http.Client
+	// Timeout of a single request (5 s if not set)
+	Timeout time.Duration
+}
+
+// Func Measure - measure the offset against the server at the URL
+func (c *Checker) Measure(ctx context.Context, url string) Sample {
+	sample := Sample{URL: url}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		sample.Err = err
+		return sample
+	}
+	// Cached responses carry the time they were generated at
+	req.Header.Set("Cache-Control", "no-cache")
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	t0 := time.Now()
+	resp, err := client.Do(req)
+	t1 := time.Now()
+	if err != nil {
+		sample.Err = err
+		return sample
+	}
+	_ = resp.Body.Close()
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		sample.Err = fmt.Errorf("%w: %q", ErrNoDate, resp.Header.Get("Date"))
+		return sample
+	}
+
+	rtt := t1.Sub(t0)
+	// Local time in the middle of the round trip, without the monotonic
+	// reading to compare it with the wall clock of the server
+	mid := t0.Add(rtt / 2).Round(0)
+
+	sample.RTT = rtt
+	sample.Offset = date.Add(dateResolution / 2).Sub(mid)
+	sample.Error = (rtt + dateResolution) / 2
+
+	return sample
+}
+
+// Func MeasureAll - measure the offsets against the servers concurrently,
+// the samples are in the order of the URLs
+func (c *Checker) MeasureAll(ctx context.Context, urls []string) []Sample {
+	samples := make([]Sample, len(urls))
+
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			samples[i] = c.Measure(ctx, url)
+		}()
+	}
+	wg.Wait()
+
+	return samples
+}
+
+// Func Combine - intersect the ranges of the successful samples
+func Combine(samples []Sample) (Estimate, error) {
+	var (
+		lowest, highest time.Duration
+		n               int
+	)
+
+	for _, s := range samples {
+		if s.Err != nil {
+			continue
+		}
+
+		low, high := s.Range()
+		if n == 0 {
+			lowest, highest = low, high
+		} else {
+			lowest, highest = max(lowest, low), min(highest, high)
+		}
+		n++
+	}
+
+	if n == 0 {
+		return Estimate{}, errors.New("no successful samples")
+	}
+
+	if lowest > highest {
+		return Estimate{Samples: n}, ErrInconsistent
+	}
+
+	return Estimate{
+		Offset:  (lowest + highest) / 2,
+		Error:   (highest - lowest) / 2,
+		Samples: n,
+	}, nil
+}
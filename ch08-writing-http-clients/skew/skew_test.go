@@ -0,0 +1,119 @@
+package skew
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testServer - server with its clock off by the offset
+func testServer(t *testing.T, offset time.Duration) string {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts.URL
+}
+
+func TestMeasureAll(t *testing.T) {
+	offsets := []time.Duration{time.Hour, -90 * time.Second, 0}
+
+	urls := make([]string, 0, len(offsets))
+	for _, offset := range offsets {
+		urls = append(urls, testServer(t, offset))
+	}
+
+	var c Checker
+	samples := c.MeasureAll(context.Background(), urls)
+
+	for i, s := range samples {
+		if s.Err != nil {
+			t.Fatal(s.Err)
+		}
+
+		if low, high := s.Range(); offsets[i] < low || offsets[i] > high {
+			t.Errorf("expected %s within [%s, %s]", offsets[i], low, high)
+		}
+
+		if s.Error < dateResolution/2 || s.Error > dateResolution/2+s.RTT {
+			t.Errorf("expected error bound (RTT + 1s) / 2; actual %s with RTT %s", s.Error, s.RTT)
+		}
+	}
+}
+
+func TestCombineServers(t *testing.T) {
+	offset := 42 * time.Second
+	urls := []string{testServer(t, offset), testServer(t, offset), testServer(t, offset)}
+
+	var c Checker
+	estimate, err := Combine(c.MeasureAll(context.Background(), urls))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if estimate.Samples != 3 {
+		t.Errorf("expected 3 samples; actual %d", estimate.Samples)
+	}
+	if (estimate.Offset - offset).Abs() > estimate.Error {
+		t.Errorf("expected %s within %s", offset, estimate)
+	}
+
+	// Servers an hour apart can't both be right
+	urls = append(urls, testServer(t, offset+time.Hour))
+	if _, err := Combine(c.MeasureAll(context.Background(), urls)); !errors.Is(err, ErrInconsistent) {
+		t.Errorf("expected ErrInconsistent; actual %v", err)
+	}
+}
+
+func TestCombine(t *testing.T) {
+	samples := []Sample{
+		{Offset: 2 * time.Second, Error: time.Second},
+		{Offset: 2500 * time.Millisecond, Error: time.Second},
+		{Err: ErrNoDate},
+	}
+
+	estimate, err := Combine(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// [1.5s, 3s] is the intersection of [1s, 3s] and [1.5s, 3.5s]
+	expected := Estimate{Offset: 2250 * time.Millisecond, Error: 750 * time.Millisecond, Samples: 2}
+	if estimate != expected {
+		t.Errorf("expected %s; actual %s", expected, estimate)
+	}
+
+	if _, err := Combine([]Sample{{Err: ErrNoDate}}); err == nil {
+		t.Error("expected an error without successful samples")
+	}
+}
+
+func TestMeasureNoDate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// Suppress the Date header the server adds
+		w.Header()["Date"] = nil
+	}))
+	defer ts.Close()
+
+	var c Checker
+	if s := c.Measure(context.Background(), ts.URL); !errors.Is(s.Err, ErrNoDate) {
+		t.Errorf("expected ErrNoDate; actual %v", s.Err)
+	}
+}
+
+func TestEstimateString(t *testing.T) {
+	for estimate, expected := range map[Estimate]string{
+		{Offset: 1500 * time.Millisecond, Error: 600 * time.Millisecond}: "+1.5s ± 600ms",
+		{Offset: -2 * time.Second, Error: time.Second}:                   "-2s ± 1s",
+	} {
+		if actual := estimate.String(); actual != expected {
+			t.Errorf("expected %q; actual %q", expected, actual)
+		}
+	}
+}
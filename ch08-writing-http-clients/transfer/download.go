@@ -0,0 +1,347 @@
+package transfer
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for the downloader
+const (
+	defaultMaxAttempts = 5
+	defaultRetryDelay  = 500 * time.Millisecond
+)
+
+// Suffixes of the file being downloaded and of its validator
+const (
+	partSuffix      = ".part"
+	validatorSuffix = ".validator"
+)
+
+// ErrChecksum is returned if the downloaded file doesn't match the checksum
+var ErrChecksum = errors.New("checksum mismatch")
+
+// Downloader saves files, resuming interrupted transfers with Range requests
+// from where they stopped (also across runs: the partial file is kept
+// next to the destination with the ".part" suffix).
+//
+// Ranges are requested with If-Range, so a file changed on the server
+// is downloaded again instead of joining two versions. The validator
+// (ETag or Last-Modified) is kept with the ".validator" suffix; a partial
+// file without one is downloaded again too.
+type Downloader struct {
+	// Client to send the requests with (http.DefaultClient if not set)
+	Client *http.Client
+	// Expected SHA-512/256 of the file in hex, as printed by the sha tool
+	// (not verified if empty). Without it, a response without Content-Length
+	// cut short by a closed connection is taken for the complete file.
+	Checksum string
+	// Maximum number of requests (5 if not set)
+	MaxAttempts int
+	// Delay before resuming a failed transfer (500 ms if not set)
+	RetryDelay time.Duration
+	// Called after every chunk received with the bytes saved so far and
+	// the total size (-1 if unknown)
+	Progress func(received, total int64)
+}
+
+// Func Download - download the URL to the path
+func (d *Downloader) Download(ctx context.Context, url, path string) error {
+	part := path + partSuffix
+	validatorPath := path + validatorSuffix
+
+	file, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	// Validator of the content to make sure resumed ranges belong to it:
+	// without one, there's no telling what the partial file holds
+	validator, err := loadValidator(validatorPath)
+	if err != nil {
+		return err
+	}
+	if validator == "" {
+		if err := truncate(file); err != nil {
+			return err
+		}
+	}
+
+	maxAttempts := d.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	retryDelay := d.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = defaultRetryDelay
+	}
+
+	var (
+		errs []error
+		done bool
+	)
+	for attempt := 0; !done; attempt++ {
+		if attempt == maxAttempts {
+			return errors.Join(append(errs, fmt.Errorf("%s: incomplete after %d attempts", url, attempt))...)
+		}
+
+		// Wait before resuming (or give up if the context is done)
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return errors.Join(append(errs, ctx.Err())...)
+			case <-time.After(retryDelay):
+			}
+		}
+
+		done, err = d.attempt(ctx, url, file, validatorPath, &validator)
+		if err != nil {
+			// The caller gave up
+			if ctx.Err() != nil {
+				return errors.Join(append(errs, err)...)
+			}
+
+			errs = append(errs, fmt.Errorf("attempt %d: %w", attempt+1, err))
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := d.verify(part); err != nil {
+		// Start over next time
+		_ = os.Remove(part)
+		_ = os.Remove(validatorPath)
+		return err
+	}
+
+	if err := os.Rename(part, path); err != nil {
+		return err
+	}
+
+	return removeValidator(validatorPath)
+}
+
+// Func attempt - request the rest of the file and append it,
+// true once the file is complete
+func (d *Downloader) attempt(
+	ctx context.Context,
+	url string,
+	file *os.File,
+	validatorPath string,
+	validator *string,
+) (bool, error) {
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	// Resume only the same content: the server sends
+	// the whole file if it has changed since
+	if offset > 0 && *validator != "" {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", *validator)
+	}
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	total := int64(-1)
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, size, err := contentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return false, err
+		}
+		if start != offset {
+			return false, fmt.Errorf("expected range from %d; actual from %d", offset, start)
+		}
+		total = size
+
+	case http.StatusOK:
+		// Range isn't supported or the file has changed: start over
+		if err := truncate(file); err != nil {
+			return false, err
+		}
+		offset = 0
+		total = resp.ContentLength
+
+		// Remember the new content's validator before any of it is
+		// saved, so that an interrupted run can resume it
+		*validator = responseValidator(resp.Header)
+		if err := saveValidator(validatorPath, *validator); err != nil {
+			return false, err
+		}
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Nothing is left if the partial file is already complete
+		_, size, err := contentRange(resp.Header.Get("Content-Range"))
+		if err == nil && size == offset {
+			return true, nil
+		}
+
+		// Otherwise it's longer than the file, start over
+		return false, truncate(file)
+
+	default:
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	progress := &progressWriter{sent: offset, total: total, report: d.Progress}
+
+	// A short body means the transfer was interrupted, the next attempt resumes it
+	n, err := io.Copy(io.MultiWriter(file, progress), resp.Body)
+	if err != nil {
+		return false, err
+	}
+
+	// The body may also end early without an error, which only the size
+	// tells if it's known (otherwise, only Checksum catches truncation)
+	if size := offset + n; total >= 0 && size != total {
+		if size > total {
+			return false, errors.Join(
+				fmt.Errorf("expected %d bytes; actual %d", total, size),
+				truncate(file),
+			)
+		}
+		return false, fmt.Errorf("incomplete: %d of %d bytes", size, total)
+	}
+
+	return true, nil
+}
+
+// Func responseValidator - validator to send in If-Range: a strong ETag
+// or Last-Modified (weak ETags can't be used for ranges), empty if none
+func responseValidator(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+
+	return header.Get("Last-Modified")
+}
+
+// Func loadValidator - validator saved by a previous run, empty if none
+func loadValidator(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+
+	return strings.TrimSpace(string(b)), err
+}
+
+// Func saveValidator - keep the validator for later runs
+// (removing the old one if there's none)
+func saveValidator(path, validator string) error {
+	if validator == "" {
+		return removeValidator(path)
+	}
+
+	return os.WriteFile(path, []byte(validator+"\n"), 0o644)
+}
+
+// Func removeValidator - remove the saved validator if there's any
+func removeValidator(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// Func verify - compare SHA-512/256 of the file with the checksum
+func (d *Downloader) verify(path string) error {
+	if d.Checksum == "" {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	hash := sha512.New512_256()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if !strings.EqualFold(actual, d.Checksum) {
+		return fmt.Errorf("%w: expected %s; actual %s", ErrChecksum, d.Checksum, actual)
+	}
+
+	return nil
+}
+
+// Func truncate - empty the file
+func truncate(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err := file.Seek(0, io.SeekStart)
+
+	return err
+}
+
+// Func contentRange - start and total size from Content-Range
+// ("bytes 100-199/1000" or "bytes */1000"), size is -1 if unknown
+func contentRange(value string) (start, size int64, err error) {
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", value)
+	}
+
+	rng, total, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", value)
+	}
+
+	size = -1
+	if total != "*" {
+		if size, err = strconv.ParseInt(total, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid Content-Range %q", value)
+		}
+	}
+
+	if rng == "*" {
+		return 0, size, nil
+	}
+
+	first, _, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", value)
+	}
+
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", value)
+	}
+
+	return start, size, nil
+}
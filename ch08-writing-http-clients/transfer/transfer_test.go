@@ -0,0 +1,381 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUpload(t *testing.T) {
+	files := []File{
+		{Field: "file0", Path: filepath.Join("..", "files", "hello.txt")},
+		{Field: "file1", Path: filepath.Join("..", "files", "goodbye.txt")},
+	}
+
+	var expectedTotal int64
+	expected := make(map[string]string)
+	for _, f := range files {
+		b, err := os.ReadFile(f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedTotal += int64(len(b))
+		expected[filepath.Base(f.Path)] = string(b)
+	}
+
+	// Collect the streamed form: the fields and the files by name
+	received := make(map[string]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength != -1 {
+			t.Errorf("expected a chunked body; actual length %d", r.ContentLength)
+		}
+
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			b, _ := io.ReadAll(part)
+			key := part.FormName()
+			if part.FileName() != "" {
+				key = part.FileName()
+			}
+			received[key] = string(b)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	var sent, total int64
+	u := Upload{
+		Fields: map[string]string{"description": "this is just my description"},
+		Files:  files,
+		Progress: func(s, t int64) {
+			sent, total = s, t
+		},
+	}
+
+	req, err := u.Request(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status %d; actual status %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	expected["description"] = "this is just my description"
+	for key, value := range expected {
+		if received[key] != value {
+			t.Errorf("%s: expected %q; actual %q", key, value, received[key])
+		}
+	}
+
+	if sent != expectedTotal || total != expectedTotal {
+		t.Errorf("expected progress %d/%d; actual %d/%d", expectedTotal, expectedTotal, sent, total)
+	}
+}
+
+func TestUploadFieldOrder(t *testing.T) {
+	u := Upload{Fields: map[string]string{"c": "3", "a": "1", "d": "4", "b": "2", "e": "5"}}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := u.write(mw, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	mr := multipart.NewReader(&body, mw.Boundary())
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, part.FormName())
+	}
+
+	if actual := strings.Join(names, ","); actual != "a,b,c,d,e" {
+		t.Errorf("expected %s; actual %s", "a,b,c,d,e", actual)
+	}
+}
+
+func TestUploadMissingFile(t *testing.T) {
+	u := Upload{Files: []File{{Field: "file", Path: "no-such-file"}}}
+
+	if _, err := u.Request(context.Background(), "http://localhost/"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing file error; actual %v", err)
+	}
+}
+
+// abortWriter fails the response after limit bytes, like a dropped connection
+type abortWriter struct {
+	http.ResponseWriter
+	limit int
+}
+
+// Func Write
+func (w *abortWriter) Write(b []byte) (int, error) {
+	if len(b) > w.limit {
+		_, _ = w.ResponseWriter.Write(b[:w.limit])
+		w.ResponseWriter.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+
+	w.limit -= len(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// testContent - server of the content, dropping the first response
+// halfway; ranges requested are reported to the channel
+func testContent(t *testing.T, content []byte, drop bool) (*httptest.Server, chan string) {
+	t.Helper()
+
+	ranges := make(chan string, 10)
+	var requests atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges <- r.Header.Get("Range")
+
+		if drop && requests.Add(1) == 1 {
+			w = &abortWriter{ResponseWriter: w, limit: len(content) / 2}
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "artifact.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, ranges
+}
+
+// testChecksum - SHA-512/256 in hex like the sha tool prints
+func testChecksum(b []byte) string {
+	sum := sha512.Sum512_256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadResume(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64<<10)
+	ts, ranges := testContent(t, content, true)
+
+	path := filepath.Join(t.TempDir(), "artifact.bin")
+
+	var received int64
+	d := Downloader{
+		Checksum:   testChecksum(content),
+		RetryDelay: time.Millisecond,
+		Progress:   func(r, _ int64) { received = r },
+	}
+
+	if err := d.Download(context.Background(), ts.URL, path); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Fatalf("expected %d bytes of content; actual %d bytes", len(content), len(b))
+	}
+	if received != int64(len(content)) {
+		t.Errorf("expected progress %d; actual %d", len(content), received)
+	}
+
+	// The second request resumed from where the first one stopped
+	close(ranges)
+	var requested []string
+	for r := range ranges {
+		requested = append(requested, r)
+	}
+	if len(requested) != 2 || requested[0] != "" || !strings.HasPrefix(requested[1], "bytes=") || requested[1] == "bytes=0-" {
+		t.Errorf("expected a full request and a resumed one; actual %q", requested)
+	}
+
+	for _, p := range []string{path + partSuffix, path + validatorSuffix} {
+		if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected %s removed; actual %v", p, err)
+		}
+	}
+}
+
+func TestDownloadPartialFile(t *testing.T) {
+	content := []byte(strings.Repeat("resume me ", 1000))
+	ts, ranges := testContent(t, content, false)
+
+	path := filepath.Join(t.TempDir(), "artifact.bin")
+	d := Downloader{Checksum: strings.ToUpper(testChecksum(content))}
+
+	testCases := []struct {
+		name      string
+		partial   []byte
+		validator string
+		requested string
+	}{
+		// a previous run stopped after 1000 bytes
+		{"resumed", content[:1000], `"v1"`, "bytes=1000-"},
+		// the complete partial file needs no more content
+		{"complete", content, `"v1"`, "bytes=10000-"},
+		// the file has changed since, If-Range gets the new one
+		{"changed", []byte(strings.Repeat("old version", 100)), `"v0"`, "bytes=1100-"},
+		// no telling what the partial file holds
+		{"no validator", []byte(strings.Repeat("unknown", 100)), "", ""},
+	}
+
+	for _, testCase := range testCases {
+		if err := os.WriteFile(path+partSuffix, testCase.partial, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := saveValidator(path+validatorSuffix, testCase.validator); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := d.Download(context.Background(), ts.URL, path); err != nil {
+			t.Fatalf("%s: %v", testCase.name, err)
+		}
+
+		if r := <-ranges; r != testCase.requested {
+			t.Errorf("%s: expected %q; actual %q", testCase.name, testCase.requested, r)
+		}
+
+		if b, err := os.ReadFile(path); err != nil || !bytes.Equal(b, content) {
+			t.Errorf("%s: expected the content; actual %d bytes, %v", testCase.name, len(b), err)
+		}
+	}
+}
+
+func TestDownloadValidatorSaved(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64<<10)
+	ts, _ := testContent(t, content, true)
+
+	path := filepath.Join(t.TempDir(), "artifact.bin")
+
+	// The run stops after the dropped response, leaving the validator
+	// for the next one
+	d := Downloader{MaxAttempts: 1}
+	if err := d.Download(context.Background(), ts.URL, path); err == nil {
+		t.Fatal("expected an incomplete download")
+	}
+
+	if v, err := loadValidator(path + validatorSuffix); err != nil || v != `"v1"` {
+		t.Errorf("expected %q saved; actual %q, %v", `"v1"`, v, err)
+	}
+}
+
+func TestDownloadShortBody(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1000))
+
+	// The first response ends cleanly, but short of the range
+	var requests atomic.Int32
+	ranges := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges <- r.Header.Get("Range")
+		w.Header().Set("ETag", `"v1"`)
+
+		if requests.Add(1) == 1 {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 1000-%d/%d", len(content)-1, len(content)))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(content[1000:1500])
+			return
+		}
+
+		http.ServeContent(w, r, "artifact.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "artifact.bin")
+	if err := os.WriteFile(path+partSuffix, content[:1000], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveValidator(path+validatorSuffix, `"v1"`); err != nil {
+		t.Fatal(err)
+	}
+
+	d := Downloader{RetryDelay: time.Millisecond}
+	if err := d.Download(context.Background(), ts.URL, path); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second request resumes after the short body
+	for _, expected := range []string{"bytes=1000-", "bytes=1500-"} {
+		if r := <-ranges; r != expected {
+			t.Errorf("expected %q; actual %q", expected, r)
+		}
+	}
+
+	if b, err := os.ReadFile(path); err != nil || !bytes.Equal(b, content) {
+		t.Errorf("expected the content; actual %d bytes, %v", len(b), err)
+	}
+}
+
+func TestDownloadChecksum(t *testing.T) {
+	content := []byte("tampered")
+	ts, _ := testContent(t, content, false)
+
+	path := filepath.Join(t.TempDir(), "artifact.bin")
+	d := Downloader{Checksum: testChecksum([]byte("original"))}
+
+	if err := d.Download(context.Background(), ts.URL, path); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("expected ErrChecksum; actual %v", err)
+	}
+
+	for _, p := range []string{path, path + partSuffix, path + validatorSuffix} {
+		if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected %s removed; actual %v", p, err)
+		}
+	}
+}
+
+func TestContentRange(t *testing.T) {
+	for value, expected := range map[string][2]int64{
+		"bytes 100-199/1000": {100, 1000},
+		"bytes 0-9/*":        {0, -1},
+		"bytes */1000":       {0, 1000},
+	} {
+		start, size, err := contentRange(value)
+		if err != nil || start != expected[0] || size != expected[1] {
+			t.Errorf("%q: expected %v; actual %d, %d, %v", value, expected, start, size, err)
+		}
+	}
+
+	for _, value := range []string{"", "items 0-1/2", "bytes 1-2", "bytes x-2/3"} {
+		if _, _, err := contentRange(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package transfer moves big files over HTTP: multipart uploads streamed
+// without buffering the body, and downloads resumed with Range requests.
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// File of a multipart upload
+type File struct {
+	// Form field name
+	Field string
+	// Path of the file, its base name is sent as the file name
+	Path string
+}
+
+// Upload is a multipart form streamed through a pipe: files are read
+// while the request is being sent, so memory use doesn't depend on their size
+type Upload struct {
+	// Form fields written before the files, ordered by name
+	Fields map[string]string
+	Files  []File
+	// Called after every chunk written with the file bytes sent so far and
+	// their total size, from the goroutine writing the body
+	Progress func(sent, total int64)
+}
+
+// Func Request - POST request with the streamed multipart body.
+// The body must be sent or closed, otherwise the writing goroutine leaks;
+// a failure reading the files fails the request body.
+func (u *Upload) Request(ctx context.Context, url string) (*http.Request, error) {
+	// Sizes are known upfront, so missing files fail before sending anything
+	var total int64
+	for _, f := range u.Files {
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		total += info.Size()
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, pr)
+	if err != nil {
+		_ = pr.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	// The body's length is unknown, it's sent chunked
+	req.ContentLength = -1
+
+	go func() {
+		// The reading side gets the error (nil means EOF)
+		_ = pw.CloseWithError(u.write(mw, total))
+	}()
+
+	return req, nil
+}
+
+// Func write - write the fields and the files to the multipart writer
+func (u *Upload) write(mw *multipart.Writer, total int64) error {
+	// Same body for the same upload, whatever the map order
+	names := make([]string, 0, len(u.Fields))
+	for name := range u.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := mw.WriteField(name, u.Fields[name]); err != nil {
+			return err
+		}
+	}
+
+	progress := &progressWriter{total: total, report: u.Progress}
+
+	for _, f := range u.Files {
+		part, err := mw.CreateFormFile(f.Field, filepath.Base(f.Path))
+		if err != nil {
+			return err
+		}
+
+		if err := copyFile(io.MultiWriter(part, progress), f.Path); err != nil {
+			return fmt.Errorf("uploading %s: %w", f.Path, err)
+		}
+	}
+
+	return mw.Close()
+}
+
+// Func copyFile - copy the file's content to the writer
+func copyFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	_, err = io.Copy(w, file)
+
+	return err
+}
+
+// progressWriter counts the bytes written and reports them
+type progressWriter struct {
+	sent   int64
+	total  int64
+	report func(sent, total int64)
+}
+
+// Func Write
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.sent += int64(len(b))
+
+	if p.report != nil {
+		p.report(p.sent, p.total)
+	}
+
+	return len(b), nil
+}
@@ -0,0 +1,37 @@
+package transport
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Func Logging - log every round trip: method, URL (without the password),
+// request id, status and the time until the response headers
+func Logging(logger *zap.Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			fields := []zap.Field{
+				zap.String("method", req.Method),
+				zap.String("url", req.URL.Redacted()),
+				zap.Duration("duration", time.Since(start)),
+			}
+			if id := req.Header.Get(RequestIDHeader); id != "" {
+				fields = append(fields, zap.String("request_id", id))
+			}
+
+			if err != nil {
+				logger.Warn("request failed", append(fields, zap.Error(err))...)
+				return nil, err
+			}
+
+			logger.Info("request", append(fields, zap.Int("status", resp.StatusCode))...)
+
+			return resp, nil
+		})
+	}
+}
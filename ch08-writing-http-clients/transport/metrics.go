@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics of the requests labeled by "host" and "status"
+// (the status code or "error" for failed round trips)
+type Metrics struct {
+	// Counter of requests
+	Requests metrics.Counter
+	// Histogram of the time until the response headers in seconds
+	Duration metrics.Histogram
+}
+
+// Func NewMetrics - metrics registered in the default prometheus registry
+// (once per namespace and subsystem)
+func NewMetrics(namespace, subsystem string) *Metrics {
+	labels := []string{"host", "status"}
+
+	return &Metrics{
+		Requests: prometheus.NewCounterFrom(
+			prom.CounterOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "client_request_count",
+				Help:      "Total client requests",
+			},
+			labels,
+		),
+		Duration: prometheus.NewHistogramFrom(
+			prom.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "client_request_duration_histogram_seconds",
+				Help:      "Duration of client requests until the response headers",
+				Buckets:   prom.DefBuckets,
+			},
+			labels,
+		),
+	}
+}
+
+// Func Measure - count and time every round trip
+func Measure(m *Metrics) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			duration := time.Since(start).Seconds()
+
+			status := "error"
+			if err == nil {
+				status = strconv.Itoa(resp.StatusCode)
+			}
+
+			labels := []string{"host", req.URL.Host, "status", status}
+			m.Requests.With(labels...).Add(1)
+			m.Duration.With(labels...).Observe(duration)
+
+			return resp, err
+		})
+	}
+}
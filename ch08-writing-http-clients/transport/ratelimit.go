@@ -0,0 +1,22 @@
+package transport
+
+import (
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// Func RateLimit - wait for the limiter before every round trip,
+// the limiter may be shared by several clients
+func RateLimit(limiter *rate.Limiter) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := limiter.Wait(req.Context()); err != nil {
+				closeBody(req)
+				return nil, err
+			}
+
+			return next.RoundTrip(req)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package transport
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header carrying the request id
+const RequestIDHeader = "X-Request-Id"
+
+// Func RequestID - set a random request id on requests without one
+func RequestID() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(RequestIDHeader) != "" {
+				return next.RoundTrip(req)
+			}
+
+			id, err := newRequestID()
+			if err != nil {
+				closeBody(req)
+				return nil, err
+			}
+
+			// Round trippers must not modify the request
+			r := req.Clone(req.Context())
+			r.Header.Set(RequestIDHeader, id)
+
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+// Func newRequestID - 16 random hex digits
+func newRequestID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,20 @@
+package transport
+
+import (
+	"learn-network-programming/ch08-writing-http-clients/client"
+	"net/http"
+)
+
+// RetryPolicy of the Retry middleware, the same as of client.Client:
+// idempotent requests with replayable bodies are retried on network
+// errors and on 408, 429, 502, 503 and 504 responses
+type RetryPolicy = client.Policy
+
+// Func Retry - retry failed round trips with exponential backoff and full jitter
+func Retry(policy RetryPolicy) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return policy.Do(req, next.RoundTrip)
+		})
+	}
+}
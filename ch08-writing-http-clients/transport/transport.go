@@ -0,0 +1,69 @@
+// Package transport composes http.RoundTripper middleware: request ids,
+// logging, metrics, retries and rate limiting around a base transport.
+package transport
+
+import (
+	"net/http"
+	"time"
+)
+
+// Middleware wraps a round tripper with extra behavior
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an ordinary function used as a round tripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// Func RoundTrip
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Builder assembles middleware around a base transport. The middleware
+// added first is the outermost one: it sees requests first and responses
+// last. A typical order is RequestID, Logging, Measure, Retry, RateLimit,
+// so that a request is logged and measured once with all its retries,
+// and every attempt is rate limited.
+type Builder struct {
+	base       http.RoundTripper
+	middleware []Middleware
+}
+
+// Func NewBuilder - builder around the base transport
+// (http.DefaultTransport if nil)
+func NewBuilder(base http.RoundTripper) *Builder {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Builder{base: base}
+}
+
+// Func Use - add the middleware inside the ones added before
+func (b *Builder) Use(middleware ...Middleware) *Builder {
+	b.middleware = append(b.middleware, middleware...)
+	return b
+}
+
+// Func Build - the base transport wrapped with the middleware
+func (b *Builder) Build() http.RoundTripper {
+	rt := b.base
+	for i := len(b.middleware) - 1; i >= 0; i-- {
+		rt = b.middleware[i](rt)
+	}
+
+	return rt
+}
+
+// Func Client - client using the built transport with the overall
+// timeout of a request (no timeout if 0)
+func (b *Builder) Client(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: b.Build(), Timeout: timeout}
+}
+
+// Func closeBody - close the request body, round trippers must do so
+// even when they fail before passing the request on
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
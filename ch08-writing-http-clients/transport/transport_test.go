@@ -0,0 +1,318 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"learn-network-programming/ch08-writing-http-clients/client"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/time/rate"
+)
+
+// testServer - server answering with the status the first failures
+// requests, echoing the request id
+func testServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(RequestIDHeader, r.Header.Get(RequestIDHeader))
+		if requests.Add(1) <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, &requests
+}
+
+// testGet - GET the URL with the client, the body is discarded
+func testGet(t *testing.T, c *http.Client, url string, header http.Header) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.DrainAndClose(resp.Body)
+
+	return resp
+}
+
+func TestBuilderOrder(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+
+	base := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		order = append(order, "base")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+
+	rt := NewBuilder(base).Use(trace("outer")).Use(trace("middle"), trace("inner")).Build()
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := strings.Join(order, ","); actual != "outer,middle,inner,base" {
+		t.Errorf("expected %q; actual %q", "outer,middle,inner,base", actual)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	ts, _ := testServer(t, 0, 0)
+	c := NewBuilder(ts.Client().Transport).Use(RequestID()).Client(time.Second)
+
+	resp := testGet(t, c, ts.URL, nil)
+	if id := resp.Header.Get(RequestIDHeader); len(id) != 16 {
+		t.Errorf("expected a generated request id; actual %q", id)
+	}
+
+	// The caller's id is kept
+	resp = testGet(t, c, ts.URL, http.Header{RequestIDHeader: {"abc"}})
+	if id := resp.Header.Get(RequestIDHeader); id != "abc" {
+		t.Errorf("expected request id %q; actual %q", "abc", id)
+	}
+
+	// The caller's request isn't modified
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.DrainAndClose(resp.Body)
+	if id := req.Header.Get(RequestIDHeader); id != "" {
+		t.Errorf("expected the request unchanged; actual id %q", id)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	ts, _ := testServer(t, 0, 0)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	c := NewBuilder(ts.Client().Transport).
+		Use(RequestID(), Logging(zap.New(core))).
+		Client(time.Second)
+
+	u, _ := url.Parse(ts.URL)
+	u.User = url.UserPassword("user", "secret")
+	testGet(t, c, u.String(), http.Header{RequestIDHeader: {"abc"}})
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 log entry; actual %d", len(entries))
+	}
+
+	fields := entries[0].ContextMap()
+	if fields["status"] != int64(http.StatusOK) || fields["request_id"] != "abc" || fields["method"] != "GET" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	if strings.Contains(fields["url"].(string), "secret") {
+		t.Errorf("expected the password redacted; actual %v", fields["url"])
+	}
+
+	// Failures are warnings
+	testFail := NewBuilder(RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, context.DeadlineExceeded
+	})).Use(Logging(zap.New(core))).Client(0)
+
+	if _, err := testFail.Get("http://example.com/"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if warnings := logs.FilterLevelExact(zapcore.WarnLevel).Len(); warnings != 1 {
+		t.Errorf("expected 1 warning; actual %d", warnings)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	ts, _ := testServer(t, 1, http.StatusServiceUnavailable)
+
+	// Unregistered vectors to inspect them in isolation
+	requests := prom.NewCounterVec(prom.CounterOpts{Name: "requests"}, []string{"host", "status"})
+	durations := prom.NewHistogramVec(prom.HistogramOpts{Name: "durations"}, []string{"host", "status"})
+	m := &Metrics{
+		Requests: prometheus.NewCounter(requests),
+		Duration: prometheus.NewHistogram(durations),
+	}
+
+	c := NewBuilder(ts.Client().Transport).Use(Measure(m)).Client(time.Second)
+	for i := 0; i < 3; i++ {
+		testGet(t, c, ts.URL, nil)
+	}
+
+	host := ts.Listener.Addr().String()
+	for status, expected := range map[string]float64{"503": 1, "200": 2} {
+		var metric dto.Metric
+		if err := requests.WithLabelValues(host, status).Write(&metric); err != nil {
+			t.Fatal(err)
+		}
+		if actual := metric.GetCounter().GetValue(); actual != expected {
+			t.Errorf("status %s: expected %v requests; actual %v", status, expected, actual)
+		}
+
+		if err := durations.WithLabelValues(host, status).(prom.Histogram).Write(&metric); err != nil {
+			t.Fatal(err)
+		}
+		if actual := metric.GetHistogram().GetSampleCount(); float64(actual) != expected {
+			t.Errorf("status %s: expected %v durations; actual %v", status, expected, actual)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ts, requests := testServer(t, 2, http.StatusServiceUnavailable)
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	c := NewBuilder(ts.Client().Transport).Use(Retry(policy)).Client(time.Second)
+
+	if resp := testGet(t, c, ts.URL, nil); resp.StatusCode != http.StatusOK || requests.Load() != 3 {
+		t.Errorf("expected status 200 after 3 requests; actual %d after %d", resp.StatusCode, requests.Load())
+	}
+
+	// POST isn't retried, the failure is returned as is
+	ts, requests = testServer(t, 2, http.StatusServiceUnavailable)
+	resp, err := c.Post(ts.URL, "text/plain", strings.NewReader("once"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.DrainAndClose(resp.Body)
+
+	if resp.StatusCode != http.StatusServiceUnavailable || requests.Load() != 1 {
+		t.Errorf("expected status 503 after 1 request; actual %d after %d", resp.StatusCode, requests.Load())
+	}
+
+	// PUT is retried with its body sent again
+	var bodies atomic.Int32
+	put := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := make([]byte, 16)
+		n, _ := r.Body.Read(b)
+		if string(b[:n]) == "again" {
+			bodies.Add(1)
+		}
+		if bodies.Load() < 2 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer put.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, put.URL, strings.NewReader("again"))
+	resp, err = c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.DrainAndClose(resp.Body)
+
+	if resp.StatusCode != http.StatusOK || bodies.Load() != 2 {
+		t.Errorf("expected status 200 after 2 bodies; actual %d after %d", resp.StatusCode, bodies.Load())
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	ts, _ := testServer(t, 0, 0)
+
+	limiter := rate.NewLimiter(rate.Every(50*time.Millisecond), 1)
+	c := NewBuilder(ts.Client().Transport).Use(RateLimit(limiter)).Client(time.Second)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		testGet(t, c, ts.URL, nil)
+	}
+
+	// The first request is free, the next three wait 50 ms each
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("expected at least 150ms; actual %s", elapsed)
+	}
+
+	// Waiting respects the request context
+	limiter = rate.NewLimiter(rate.Every(time.Hour), 1)
+	limiter.Allow()
+	c = NewBuilder(ts.Client().Transport).Use(RateLimit(limiter)).Client(50 * time.Millisecond)
+	if _, err := c.Get(ts.URL); err == nil {
+		t.Error("expected an error waiting for the limiter")
+	}
+
+	// The body is closed even though the request never goes out
+	body := &closeTracker{Reader: strings.NewReader("never sent")}
+	req, _ := http.NewRequest(http.MethodPost, ts.URL, body)
+	if _, err := c.Do(req); err == nil {
+		t.Error("expected an error waiting for the limiter")
+	}
+	if !body.closed.Load() {
+		t.Error("expected the request body closed")
+	}
+}
+
+// closeTracker is a request body remembering whether it was closed
+type closeTracker struct {
+	*strings.Reader
+	closed atomic.Bool
+}
+
+// Func Close
+func (c *closeTracker) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// Runs of TestNewMetrics, registering the metrics again fails
+var newMetricsRuns atomic.Int32
+
+func TestNewMetrics(t *testing.T) {
+	subsystem := fmt.Sprintf("transport%d", newMetricsRuns.Add(1))
+	m := NewMetrics("test", subsystem)
+
+	c := NewBuilder(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody, Request: req}, nil
+	})).Use(Measure(m)).Client(0)
+
+	resp, err := c.Get("http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.DrainAndClose(resp.Body)
+
+	families, err := prom.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	for _, family := range families {
+		if family.GetName() == "test_"+subsystem+"_client_request_count" {
+			n = len(family.GetMetric())
+		}
+	}
+	if n != 1 {
+		t.Errorf("expected 1 registered series; actual %d", n)
+	}
+}
@@ -0,0 +1,211 @@
+// Package config describes the ch9 server in a TOML file: listeners,
+// static mounts, pages with server push targets, redirects and middleware.
+// See server/server.toml for an example.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"learn-network-programming/ch09-building-http-services/restrict"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml"
+)
+
+// Config is the whole server configuration
+type Config struct {
+	Listeners  []Listener `toml:"listeners"`
+	Middleware Middleware `toml:"middleware"`
+	Static     []Static   `toml:"static"`
+	Pages      []Page     `toml:"pages"`
+	Redirects  []Redirect `toml:"redirects"`
+	Shutdown   Shutdown   `toml:"shutdown"`
+}
+
+// Listener is an address to serve on, over TLS if both the certificate
+// and the private key are set
+type Listener struct {
+	Address string `toml:"address"`
+	// Name of a socket passed with systemd socket activation to use
+	// instead of the address if there's one ("*" for any name)
+	Socket string `toml:"socket"`
+	Cert   string `toml:"cert"`
+	Key    string `toml:"key"`
+}
+
+// Middleware wraps all the routes, the ones not enabled are left out
+type Middleware struct {
+	RequestID bool `toml:"request_id"`
+	Recover   bool `toml:"recover"`
+	Compress  bool `toml:"compress"`
+	// Limit of the request body size in bytes (no limit if zero)
+	MaxBodySize int64 `toml:"max_body_size"`
+	// Security headers (none if the table is missing)
+	Security *Security `toml:"security"`
+}
+
+// Security are the security headers (middleware.SecurityOptions)
+type Security struct {
+	HSTSMaxAge            time.Duration `toml:"hsts_max_age"`
+	HSTSIncludeSubdomains bool          `toml:"hsts_include_subdomains"`
+	HSTSPreload           bool          `toml:"hsts_preload"`
+	ContentSecurityPolicy string        `toml:"content_security_policy"`
+	FrameOptions          string        `toml:"frame_options"`
+	ReferrerPolicy        string        `toml:"referrer_policy"`
+}
+
+// Static serves the files of a directory below the prefix
+type Static struct {
+	// Path prefix ending with "/", e.g., "/static/"
+	Prefix string `toml:"prefix"`
+	Dir    string `toml:"dir"`
+	// Restricted files (hidden ones if no rules are set)
+	Restrict restrict.Rules `toml:"restrict"`
+	// Origins allowed to read the files from their pages ("*" for any)
+	CORSOrigins []string `toml:"cors_origins"`
+}
+
+// Page serves a file at the path, pushing the targets over HTTP/2
+type Page struct {
+	Path string   `toml:"path"`
+	File string   `toml:"file"`
+	Push []string `toml:"push"`
+}
+
+// Redirect sends requests for the path elsewhere
+type Redirect struct {
+	From string `toml:"from"`
+	To   string `toml:"to"`
+	// Redirection status (301 if not set)
+	Status int `toml:"status"`
+}
+
+// Shutdown controls stopping on SIGINT and SIGTERM
+type Shutdown struct {
+	// Time between reporting not ready and closing the listeners,
+	// for load balancers to stop sending requests (none if zero)
+	ReadyDelay time.Duration `toml:"ready_delay"`
+	// Time the requests in flight have to finish before their
+	// connections are closed (30 s if not set)
+	DrainTimeout time.Duration `toml:"drain_timeout"`
+	// Path of the readiness endpoint, e.g., "/readyz" (none if empty)
+	ReadinessPath string `toml:"readiness_path"`
+}
+
+// DefaultDrainTimeout is the drain timeout if none is set
+const DefaultDrainTimeout = 30 * time.Second
+
+// Func Load - read and validate the configuration file; relative
+// paths of files are resolved against the file's directory
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var c Config
+	// unknown keys are likely typos
+	if err := toml.NewDecoder(f).Strict(true).Decode(&c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	c.resolve(filepath.Dir(path))
+	if c.Shutdown.DrainTimeout == 0 {
+		c.Shutdown.DrainTimeout = DefaultDrainTimeout
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &c, nil
+}
+
+// Func resolve - make relative paths of files relative to the directory
+func (c *Config) resolve(dir string) {
+	join := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	for i := range c.Listeners {
+		c.Listeners[i].Cert = join(c.Listeners[i].Cert)
+		c.Listeners[i].Key = join(c.Listeners[i].Key)
+	}
+	for i := range c.Static {
+		c.Static[i].Dir = join(c.Static[i].Dir)
+	}
+	for i := range c.Pages {
+		c.Pages[i].File = join(c.Pages[i].File)
+	}
+}
+
+// Func Validate - check the configuration for mistakes
+// which would only show when serving
+func (c *Config) Validate() error {
+	var errs []error
+
+	if len(c.Listeners) == 0 {
+		errs = append(errs, errors.New("no listeners"))
+	}
+	for i, l := range c.Listeners {
+		if l.Address == "" && l.Socket == "" {
+			errs = append(errs, fmt.Errorf("listener %d: no address", i))
+		}
+		if (l.Cert == "") != (l.Key == "") {
+			errs = append(errs, fmt.Errorf("listener %d: TLS needs both the certificate and the key", i))
+		}
+	}
+
+	if c.Middleware.MaxBodySize < 0 {
+		errs = append(errs, errors.New("negative max_body_size"))
+	}
+
+	for i, s := range c.Static {
+		if !strings.HasPrefix(s.Prefix, "/") || !strings.HasSuffix(s.Prefix, "/") {
+			errs = append(errs, fmt.Errorf("static %d: prefix %q must start and end with /", i, s.Prefix))
+		}
+		if info, err := os.Stat(s.Dir); err != nil {
+			errs = append(errs, fmt.Errorf("static %d: %w", i, err))
+		} else if !info.IsDir() {
+			errs = append(errs, fmt.Errorf("static %d: %s isn't a directory", i, s.Dir))
+		}
+		if _, err := restrict.Compile(s.Restrict); err != nil {
+			errs = append(errs, fmt.Errorf("static %d: %w", i, err))
+		}
+	}
+
+	for i, p := range c.Pages {
+		if !strings.HasPrefix(p.Path, "/") {
+			errs = append(errs, fmt.Errorf("page %d: path %q must start with /", i, p.Path))
+		}
+		if _, err := os.Stat(p.File); err != nil {
+			errs = append(errs, fmt.Errorf("page %d: %w", i, err))
+		}
+	}
+
+	for i, r := range c.Redirects {
+		if !strings.HasPrefix(r.From, "/") || r.To == "" {
+			errs = append(errs, fmt.Errorf("redirect %d: needs a path to redirect from and a target", i))
+		}
+		if r.Status != 0 && (r.Status < http.StatusMultipleChoices || r.Status > http.StatusPermanentRedirect) {
+			errs = append(errs, fmt.Errorf("redirect %d: status %d isn't a redirection", i, r.Status))
+		}
+	}
+
+	if c.Shutdown.ReadyDelay < 0 || c.Shutdown.DrainTimeout < 0 {
+		errs = append(errs, errors.New("negative shutdown durations"))
+	}
+	if p := c.Shutdown.ReadinessPath; p != "" && !strings.HasPrefix(p, "/") {
+		errs = append(errs, fmt.Errorf("readiness path %q must start with /", p))
+	}
+
+	return errors.Join(errs...)
+}
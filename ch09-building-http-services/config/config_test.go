@@ -0,0 +1,234 @@
+package config_test
+
+import (
+	"learn-network-programming/ch09-building-http-services/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Func testConfig - write the configuration to a file in a temporary
+// directory with a "files" subdirectory holding index.html
+func testConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "files"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		"index.html": "<html>index</html>",
+		".secret":    "secret",
+		"old.bak":    "backup",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, "files", name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dir, "server.toml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// Func TestLoadExample - the example configuration stays valid
+func TestLoadExample(t *testing.T) {
+	cfg, err := config.Load("../server/server.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := len(cfg.Listeners); actual != 2 {
+		t.Errorf("expected 2 listeners; actual %d", actual)
+	}
+	if actual := cfg.Middleware.Security.HSTSMaxAge; actual != 365*24*time.Hour {
+		t.Errorf("expected HSTS max-age of a year; actual %s", actual)
+	}
+	if actual := cfg.Static[0].Restrict.Globs; len(actual) != 1 || actual[0] != "*.bak" {
+		t.Errorf("expected glob *.bak; actual %v", actual)
+	}
+	if actual := cfg.Pages[0].Push; len(actual) != 2 {
+		t.Errorf("expected 2 push targets; actual %v", actual)
+	}
+	if actual := cfg.Shutdown.ReadyDelay; actual != 2*time.Second {
+		t.Errorf("expected ready delay of 2s; actual %s", actual)
+	}
+
+	if _, err := cfg.Handler(nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Func TestHandler
+func TestHandler(t *testing.T) {
+	path := testConfig(t, `
+[[listeners]]
+address = '127.0.0.1:0'
+
+[middleware]
+request_id = true
+compress = true
+[middleware.security]
+frame_options = 'DENY'
+
+[[static]]
+prefix = '/static/'
+dir = 'files'
+[static.restrict]
+globs = ['*.bak']
+status = 403
+
+[[pages]]
+path = '/'
+file = 'files/index.html'
+
+[[redirects]]
+from = '/old'
+to = '/'
+status = 308
+`)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := cfg.Handler(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		method   string
+		path     string
+		code     int
+		body     string
+		location string
+	}{
+		{http.MethodGet, "/", http.StatusOK, "<html>index</html>", ""},
+		{http.MethodPost, "/", http.StatusMethodNotAllowed, "Method not allowed\n", ""},
+		{http.MethodGet, "/static/index.html", http.StatusMovedPermanently, "", "./"},
+		{http.MethodGet, "/static/old.bak", http.StatusForbidden, "Forbidden\n", ""},
+		// only the rules given restrict
+		{http.MethodGet, "/static/.secret", http.StatusOK, "secret", ""},
+		{http.MethodPost, "/old", http.StatusPermanentRedirect, "", "/"},
+	}
+
+	for i, testCase := range testCases {
+		req := httptest.NewRequest(testCase.method, "http://test"+testCase.path, nil)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if actual := rec.Code; actual != testCase.code {
+			t.Errorf("%d: expected %d; actual %d", i, testCase.code, actual)
+		}
+		if testCase.body != "" && rec.Body.String() != testCase.body {
+			t.Errorf("%d: expected body %q; actual %q", i, testCase.body, rec.Body.String())
+		}
+		if actual := rec.Header().Get("Location"); actual != testCase.location {
+			t.Errorf("%d: expected location %q; actual %q", i, testCase.location, actual)
+		}
+
+		// the middleware wraps all routes
+		if rec.Header().Get("X-Request-Id") == "" || rec.Header().Get("X-Frame-Options") != "DENY" {
+			t.Errorf("%d: missing middleware headers: %v", i, rec.Header())
+		}
+	}
+}
+
+// Func TestDefaultRestrict - static mounts without rules hide hidden files
+func TestDefaultRestrict(t *testing.T) {
+	path := testConfig(t, `
+[[listeners]]
+address = '127.0.0.1:0'
+
+[[static]]
+prefix = '/'
+dir = 'files'
+`)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := cfg.Handler(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://test/.secret", nil))
+
+	if actual := rec.Code; actual != http.StatusNotFound {
+		t.Errorf("expected %d; actual %d", http.StatusNotFound, actual)
+	}
+}
+
+// Func TestInvalid - configurations rejected by Load and Handler
+func TestInvalid(t *testing.T) {
+	testCases := []struct {
+		content string
+		err     string
+	}{
+		{``, "no listeners"},
+		{"[[listeners]]\nadress = ':80'", "undecoded keys"},
+		{"[[listeners]]\naddress = ':80'\ncert = 'c.pem'", "both the certificate and the key"},
+		{"[[listeners]]\naddress = ':80'\n[[static]]\nprefix = '/static'\ndir = 'files'", "must start and end with /"},
+		{"[[listeners]]\naddress = ':80'\n[[static]]\nprefix = '/s/'\ndir = 'missing'", "no such file"},
+		{"[[listeners]]\naddress = ':80'\n[[static]]\nprefix = '/s/'\ndir = 'files'\n[static.restrict]\nregexps = ['(']", "regexp"},
+		{"[[listeners]]\naddress = ':80'\n[[pages]]\npath = '/'\nfile = 'missing.html'", "no such file"},
+		{"[[listeners]]\naddress = ':80'\n[[redirects]]\nfrom = '/a'\nto = '/b'\nstatus = 200", "isn't a redirection"},
+		{"[[listeners]]\naddress = ':80'\n[middleware]\nmax_body_size = -1", "negative"},
+		{"[[listeners]]\naddress = ':80'\n[shutdown]\ndrain_timeout = '-1s'", "negative shutdown"},
+		{"[[listeners]]\naddress = ':80'\n[shutdown]\nreadiness_path = 'readyz'", "must start with /"},
+	}
+
+	for i, testCase := range testCases {
+		_, err := config.Load(testConfig(t, testCase.content))
+		if err == nil || !strings.Contains(err.Error(), testCase.err) {
+			t.Errorf("%d: expected error with %q; actual %v", i, testCase.err, err)
+		}
+	}
+
+	// conflicting routes are found when building the handler
+	cfg, err := config.Load(testConfig(t, `
+[[listeners]]
+address = ':80'
+[[pages]]
+path = '/a'
+file = 'files/index.html'
+[[redirects]]
+from = '/a'
+to = '/b'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.Handler(nil); err == nil {
+		t.Error("expected an error for the conflicting routes")
+	}
+
+	// missing files are errors too
+	if _, err := config.Load(filepath.Join(t.TempDir(), "missing.toml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+// Func TestDefaultDrainTimeout
+func TestDefaultDrainTimeout(t *testing.T) {
+	cfg, err := config.Load(testConfig(t, "[[listeners]]\naddress = ':80'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := cfg.Shutdown.DrainTimeout; actual != config.DefaultDrainTimeout {
+		t.Errorf("expected %s; actual %s", config.DefaultDrainTimeout, actual)
+	}
+}
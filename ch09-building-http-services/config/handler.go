@@ -0,0 +1,148 @@
+package config
+
+import (
+	"fmt"
+	"learn-network-programming/ch09-building-http-services/middleware"
+	"learn-network-programming/ch09-building-http-services/restrict"
+	"learn-network-programming/ch09-building-http-services/router"
+	"log"
+	"net/http"
+)
+
+// Methods redirects are registered for
+var redirectMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// Func Handler - the routes wrapped in the middleware, failed
+// push attempts are logged to the logger (log.Default() if nil)
+func (c *Config) Handler(logger *log.Logger) (handler http.Handler, err error) {
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	// the router panics on conflicting patterns
+	defer func() {
+		if v := recover(); v != nil {
+			handler, err = nil, fmt.Errorf("routes: %v", v)
+		}
+	}()
+
+	r := router.New()
+
+	for _, s := range c.Static {
+		static, err := s.handler()
+		if err != nil {
+			return nil, err
+		}
+
+		r.Handle(http.MethodGet, s.Prefix, static)
+		r.Handle(http.MethodHead, s.Prefix, static)
+	}
+
+	for _, p := range c.Pages {
+		r.Handle(http.MethodGet, p.Path, p.handler(logger))
+	}
+
+	for _, rd := range c.Redirects {
+		status := rd.Status
+		if status == 0 {
+			status = http.StatusMovedPermanently
+		}
+
+		redirect := http.RedirectHandler(rd.To, status)
+		for _, method := range redirectMethods {
+			r.Handle(method, rd.From, redirect)
+		}
+	}
+
+	return c.Middleware.wrap(r, logger), nil
+}
+
+// Func handler - file server of the directory
+func (s Static) handler() (http.Handler, error) {
+	rules := s.Restrict
+	if len(rules.Prefixes) == 0 && len(rules.Globs) == 0 && len(rules.Regexps) == 0 {
+		rules.Prefixes = []string{"."}
+	}
+
+	matcher, err := restrict.Compile(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := http.StripPrefix(
+		s.Prefix,
+		middleware.Restrict(
+			matcher,
+			http.FileServer(http.Dir(s.Dir)),
+		),
+	)
+
+	if len(s.CORSOrigins) > 0 {
+		handler = middleware.CORS(
+			middleware.CORSOptions{AllowedOrigins: s.CORSOrigins},
+			handler,
+		)
+	}
+
+	return handler, nil
+}
+
+// Func handler - serve the file and push the targets if possible
+func (p Page) handler(logger *log.Logger) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if pusher, ok := w.(http.Pusher); ok {
+				for _, target := range p.Push {
+					err := pusher.Push(target, nil)
+					if err != nil {
+						logger.Printf("%s push failed: %v", target, err)
+					}
+				}
+			}
+
+			http.ServeFile(w, r, p.File)
+		},
+	)
+}
+
+// Func wrap - wrap the handler in the enabled middleware, outermost first:
+// request IDs, panic recovery, security headers, compression, a limit
+// on the request body and draining it for connection reuse
+func (m Middleware) wrap(handler http.Handler, logger *log.Logger) http.Handler {
+	handler = middleware.DrainAndClose(handler)
+
+	if m.MaxBodySize > 0 {
+		handler = middleware.LimitBody(m.MaxBodySize, handler)
+	}
+	if m.Compress {
+		handler = middleware.Compress(nil, handler)
+	}
+	if s := m.Security; s != nil {
+		handler = middleware.SecurityHeaders(
+			middleware.SecurityOptions{
+				HSTSMaxAge:            s.HSTSMaxAge,
+				HSTSIncludeSubdomains: s.HSTSIncludeSubdomains,
+				HSTSPreload:           s.HSTSPreload,
+				ContentSecurityPolicy: s.ContentSecurityPolicy,
+				FrameOptions:          s.FrameOptions,
+				ReferrerPolicy:        s.ReferrerPolicy,
+			},
+			handler,
+		)
+	}
+	if m.Recover {
+		handler = middleware.Recover(logger, handler)
+	}
+	if m.RequestID {
+		handler = middleware.RequestID(handler)
+	}
+
+	return handler
+}
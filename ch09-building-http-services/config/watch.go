@@ -0,0 +1,103 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Editors and deployment tools change a file in several steps,
+// changes closer together than that cause a single reload
+const settleDelay = 100 * time.Millisecond
+
+// Func Watch - call reload on SIGHUP and whenever the file changes,
+// until the context is done. The directory is watched rather than
+// the file since replacing the file (write to a temporary file and
+// rename) ends the watch of the original one.
+//
+// Watcher errors are logged to the logger (log.Default() if nil) and
+// watching goes on; if the directory can't be watched at all, the error
+// is logged and only SIGHUP reloads. Once Watch returns, SIGHUP stays
+// ignored rather than terminating the process, as it would by default.
+func Watch(ctx context.Context, path string, reload func(), logger *log.Logger) error {
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Ignore(syscall.SIGHUP)
+
+	path = filepath.Clean(path)
+
+	// Without a watcher, the nil channels never fire
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+	)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		defer func() { _ = watcher.Close() }()
+		err = watcher.Add(filepath.Dir(path))
+	}
+	if err != nil {
+		logger.Printf("watch %s: %v (reloading on SIGHUP only)", path, err)
+	} else {
+		events, errs = watcher.Events, watcher.Errors
+	}
+
+	return watch(ctx, path, events, errs, hup, reload, logger)
+}
+
+// Func watch - the loop of Watch
+func watch(
+	ctx context.Context,
+	path string,
+	events <-chan fsnotify.Event,
+	errs <-chan error,
+	hup <-chan os.Signal,
+	reload func(),
+	logger *log.Logger,
+) error {
+	// fires once the changes have settled
+	var settled <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			reload()
+		case <-settled:
+			settled = nil
+			reload()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			const changes = fsnotify.Create | fsnotify.Write | fsnotify.Rename
+			if filepath.Clean(event.Name) == path && event.Op&changes != 0 {
+				settled = time.After(settleDelay)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				return nil
+			}
+			logger.Printf("watch %s: %v", path, err)
+
+			// The change may be among the events lost
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				settled = time.After(settleDelay)
+			}
+		}
+	}
+}
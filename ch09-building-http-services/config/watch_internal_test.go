@@ -0,0 +1,60 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Func TestWatchErrors - watcher errors are logged and watching goes on
+func TestWatchErrors(t *testing.T) {
+	events := make(chan fsnotify.Event)
+	errs := make(chan error)
+	hup := make(chan os.Signal)
+	reloads := make(chan struct{}, 10)
+
+	var logs bytes.Buffer
+	logger := log.New(&logs, "", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- watch(ctx, "server.toml", events, errs, hup, func() { reloads <- struct{}{} }, logger)
+	}()
+
+	errs <- errors.New("watch failed")
+
+	// the lost events may have changed the file
+	errs <- fsnotify.ErrEventOverflow
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after an overflow")
+	}
+
+	// still watching
+	events <- fsnotify.Event{Name: "server.toml", Op: fsnotify.Write}
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after the errors")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+
+	for _, expected := range []string{"watch failed", fsnotify.ErrEventOverflow.Error()} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("expected %q logged; actual %q", expected, logs.String())
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package config_test
+
+import (
+	"context"
+	"learn-network-programming/ch09-building-http-services/config"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Func TestWatch - reload on writes, on replacing the file and on SIGHUP
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.toml")
+	if err := os.WriteFile(path, []byte("# v1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reloads := make(chan struct{}, 10)
+	done := make(chan error)
+	go func() {
+		done <- config.Watch(ctx, path, func() { reloads <- struct{}{} }, nil)
+	}()
+
+	// wait for a single reload
+	expectReload := func(what string) {
+		t.Helper()
+
+		select {
+		case <-reloads:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no reload after %s", what)
+		}
+	}
+
+	// give the watcher time to start
+	time.Sleep(100 * time.Millisecond)
+
+	// several writes in a row reload once
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(path, []byte("# v2\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectReload("writes")
+
+	// other files in the directory don't matter
+	if err := os.WriteFile(filepath.Join(dir, "other"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// replacing the file with a renamed one
+	tmp := filepath.Join(dir, "server.toml.tmp")
+	if err := os.WriteFile(tmp, []byte("# v3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	expectReload("rename")
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	expectReload("SIGHUP")
+
+	select {
+	case <-reloads:
+		t.Error("unexpected reload")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+
+	// SIGHUP meant as a reload doesn't terminate the process once
+	// the watch is over
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+}
+
+// logWriter - log output sent to a channel line by line
+type logWriter chan string
+
+// Func Write
+func (w logWriter) Write(b []byte) (int, error) {
+	w <- string(b)
+	return len(b), nil
+}
+
+// Func TestWatchUnwatchable - SIGHUP reloads even if the file can't be watched
+func TestWatchUnwatchable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "server.toml")
+
+	logs := make(logWriter, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	reloads := make(chan struct{}, 10)
+	done := make(chan error)
+	go func() {
+		done <- config.Watch(ctx, path, func() { reloads <- struct{}{} }, log.New(logs, "", 0))
+	}()
+
+	// the error is logged once SIGHUP is handled
+	select {
+	case line := <-logs:
+		if !strings.Contains(line, path) {
+			t.Errorf("expected the error about %s logged; actual %q", path, line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error logged")
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after SIGHUP")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,104 @@
+// Package graceful stops HTTP servers without cutting requests short:
+// the server reports not ready, waits for load balancers to notice,
+// stops accepting connections and gives the requests in flight
+// a deadline to finish before closing their connections.
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrForced is returned if connections had to be closed
+// with requests still in flight
+var ErrForced = errors.New("shutdown forced")
+
+// Readiness tells whether the server takes new requests,
+// for health checks of load balancers and orchestrators
+type Readiness struct {
+	ready atomic.Bool
+}
+
+// Func Set
+func (r *Readiness) Set(ready bool) {
+	r.ready.Store(ready)
+}
+
+// Func Ready
+func (r *Readiness) Ready() bool {
+	return r.ready.Load()
+}
+
+// Func ServeHTTP - reply with 200 if ready, 503 otherwise
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	// health checks must see the current state
+	w.Header().Set("Cache-Control", "no-store")
+
+	if !r.Ready() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	_, _ = w.Write([]byte("ready\n"))
+}
+
+// Func Route - serve the readiness at the path, pass other requests
+// to the handler (no readiness endpoint if the path is empty)
+func (r *Readiness) Route(path string, next http.Handler) http.Handler {
+	if path == "" {
+		return next
+	}
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == path {
+				r.ServeHTTP(w, req)
+				return
+			}
+
+			next.ServeHTTP(w, req)
+		},
+	)
+}
+
+// Func Shutdown - report not ready, keep serving for the delay, then shut
+// the server down giving the requests in flight the timeout to finish
+// (no limit if zero). Connections are closed right away once the
+// timeout expires or ctx is done, ErrForced is returned then.
+func Shutdown(
+	ctx context.Context,
+	server *http.Server,
+	readiness *Readiness,
+	delay time.Duration,
+	timeout time.Duration,
+) error {
+	if readiness != nil {
+		readiness.Set(false)
+	}
+
+	// load balancers stop sending requests after a failed health check
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// stop accepting, close idle connections and wait for the active ones
+	if err := server.Shutdown(ctx); err != nil {
+		_ = server.Close()
+		return fmt.Errorf("%w: %v", ErrForced, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package graceful_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"learn-network-programming/ch09-building-http-services/graceful"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Func testServer - server with a long request at /long lasting until
+// release is closed, and the readiness at /ready; started is closed once
+// the long request is being handled
+func testServer(t *testing.T, release <-chan struct{}) (*http.Server, *graceful.Readiness, string, <-chan struct{}) {
+	t.Helper()
+
+	started := make(chan struct{})
+	readiness := new(graceful.Readiness)
+	readiness.Set(true)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/long", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-release:
+			_, _ = io.WriteString(w, "done")
+		case <-r.Context().Done():
+		}
+	})
+
+	server := &http.Server{Handler: readiness.Route("/ready", mux)}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	return server, readiness, "http://" + listener.Addr().String(), started
+}
+
+// Func get - body of the response or the error
+func get(url string) (string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+
+	return resp.Status + " " + string(body), err
+}
+
+// Func TestShutdownDrains - the long request finishes during the shutdown
+func TestShutdownDrains(t *testing.T) {
+	release := make(chan struct{})
+	server, readiness, url, started := testServer(t, release)
+
+	if body, err := get(url + "/ready"); err != nil || body != "200 OK ready\n" {
+		t.Fatalf("expected ready; actual %q (%v)", body, err)
+	}
+
+	// start the long request
+	result := make(chan string)
+	go func() {
+		body, err := get(url + "/long")
+		if err != nil {
+			body = err.Error()
+		}
+		result <- body
+	}()
+	<-started
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- graceful.Shutdown(context.Background(), server, readiness, 200*time.Millisecond, 5*time.Second)
+	}()
+
+	// not ready, but still serving during the delay
+	time.Sleep(50 * time.Millisecond)
+	if readiness.Ready() {
+		t.Error("expected not ready")
+	}
+	if body, err := get(url + "/ready"); err != nil || body != "503 Service Unavailable not ready\n" {
+		t.Errorf("expected not ready; actual %q (%v)", body, err)
+	}
+
+	// no new connections once draining
+	time.Sleep(300 * time.Millisecond)
+	if _, err := net.DialTimeout("tcp", url[len("http://"):], time.Second); err == nil {
+		t.Error("expected new connections to be refused")
+	}
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("shutdown returned with a request in flight: %v", err)
+	default:
+	}
+
+	close(release)
+
+	if body := <-result; body != "200 OK done" {
+		t.Errorf("expected the long request to finish; actual %q", body)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("expected a graceful shutdown; actual %v", err)
+	}
+}
+
+// Func TestShutdownForced - connections are closed after the drain timeout
+func TestShutdownForced(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	server, readiness, url, started := testServer(t, release)
+
+	result := make(chan error)
+	go func() {
+		_, err := get(url + "/long")
+		result <- err
+	}()
+	<-started
+
+	begin := time.Now()
+	err := graceful.Shutdown(context.Background(), server, readiness, 0, 200*time.Millisecond)
+	if !errors.Is(err, graceful.ErrForced) {
+		t.Errorf("expected %v; actual %v", graceful.ErrForced, err)
+	}
+	if elapsed := time.Since(begin); elapsed > 2*time.Second {
+		t.Errorf("expected the timeout to end the shutdown; took %s", elapsed)
+	}
+
+	if err := <-result; err == nil {
+		t.Error("expected the long request to be cut off")
+	}
+}
+
+// Func TestShutdownCanceled - canceling the context forces the shutdown
+// without waiting for the delay
+func TestShutdownCanceled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	server, readiness, url, started := testServer(t, release)
+
+	go func() { _, _ = get(url + "/long") }()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	begin := time.Now()
+	err := graceful.Shutdown(ctx, server, readiness, time.Minute, time.Minute)
+	if !errors.Is(err, graceful.ErrForced) {
+		t.Errorf("expected %v; actual %v", graceful.ErrForced, err)
+	}
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Errorf("expected the cancellation to end the shutdown; took %s", elapsed)
+	}
+}
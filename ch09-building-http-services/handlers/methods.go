@@ -63,15 +63,15 @@ func (m Methods) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// add "Allow" header entry with the list of supported methods
-	w.Header().Add("Allow", m.allowedMethods())
+	w.Header().Add("Allow", m.Allowed())
 	// if the client didn't explicitly ask for the method list, reply with 405
 	if r.Method != http.MethodOptions {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
-// Func allowedMethods
-func (m Methods) allowedMethods() string {
+// Func Allowed (value of the "Allow" header)
+func (m Methods) Allowed() string {
 	// concatenate sorted keys from the map
 	methodList := make([]string, 0, len(m))
 
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Swap is a handler which can be replaced while serving: requests
+// in flight finish with the handler they started with, new ones
+// get the replacement
+type Swap struct {
+	current atomic.Pointer[http.Handler]
+}
+
+// Func NewSwap - swap serving with the handler
+func NewSwap(handler http.Handler) *Swap {
+	s := new(Swap)
+	s.Store(handler)
+
+	return s
+}
+
+// Func Store - replace the handler
+func (s *Swap) Store(handler http.Handler) {
+	s.current.Store(&handler)
+}
+
+// Func ServeHTTP
+func (s *Swap) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*s.current.Load()).ServeHTTP(w, r)
+}
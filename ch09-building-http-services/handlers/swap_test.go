@@ -0,0 +1,45 @@
+package handlers_test
+
+import (
+	"io"
+	"learn-network-programming/ch09-building-http-services/handlers"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Func TestSwap - replace the handler while requests are being served
+func TestSwap(t *testing.T) {
+	reply := func(body string) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(w, body)
+			},
+		)
+	}
+
+	swap := handlers.NewSwap(reply("old"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rec := httptest.NewRecorder()
+			swap.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://test/", nil))
+			if body := rec.Body.String(); body != "old" && body != "new" {
+				t.Errorf("unexpected body %q", body)
+			}
+		}()
+	}
+	swap.Store(reply("new"))
+	wg.Wait()
+
+	rec := httptest.NewRecorder()
+	swap.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://test/", nil))
+	if actual := rec.Body.String(); actual != "new" {
+		t.Errorf("expected %q; actual %q", "new", actual)
+	}
+}
@@ -0,0 +1,296 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Responses shorter than that aren't worth compressing
+const minCompressSize = 1024
+
+// Encoding is a content coding the responses can be compressed with
+type Encoding struct {
+	// Token in Accept-Encoding and Content-Encoding, e.g., "gzip"
+	Name string
+	// Func NewWriter - writer compressing into w, closed at the end of the response
+	NewWriter func(w io.Writer) io.WriteCloser
+}
+
+// Func Gzip - gzip encoding with the compression level (gzip.DefaultCompression
+// if the level is invalid)
+func Gzip(level int) Encoding {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+
+	return Encoding{
+		Name: "gzip",
+		NewWriter: func(w io.Writer) io.WriteCloser {
+			// the level has been checked already
+			zw, _ := gzip.NewWriterLevel(w, level)
+			return zw
+		},
+	}
+}
+
+// Func Brotli - brotli encoding with the compression level (brotli.DefaultCompression
+// if the level is invalid)
+func Brotli(level int) Encoding {
+	if level < brotli.BestSpeed || level > brotli.BestCompression {
+		level = brotli.DefaultCompression
+	}
+
+	return Encoding{
+		Name: "br",
+		NewWriter: func(w io.Writer) io.WriteCloser {
+			return brotli.NewWriterLevel(w, level)
+		},
+	}
+}
+
+// Func Compress - compress responses with the encoding the client accepts,
+// the encodings in the order of preference of the server (brotli, then gzip
+// if none, both with the default level).
+//
+// Responses are left alone if they're already encoded, partial (206),
+// without a body, shorter than 1 KiB, or of a type which
+// doesn't compress well (only text, JSON, JavaScript, XML and SVG do).
+func Compress(encodings []Encoding, next http.Handler) http.Handler {
+	if len(encodings) == 0 {
+		encodings = []Encoding{Brotli(brotli.DefaultCompression), Gzip(gzip.DefaultCompression)}
+	}
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			// caches must keep the variants apart
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding, ok := negotiate(r.Header.Get("Accept-Encoding"), encodings)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// not deferred: after a panic Recover may still reply with 500
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+			next.ServeHTTP(wrap(cw, w), r)
+			cw.close()
+		},
+	)
+}
+
+// Func negotiate - the encoding with the highest quality value in Accept-Encoding,
+// the first one of the server on a tie
+func negotiate(accept string, encodings []Encoding) (Encoding, bool) {
+	// quality values by coding
+	values := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(strings.ToLower(key)) != "q" {
+				continue
+			}
+
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				parsed = 0
+			}
+			q = parsed
+		}
+
+		values[name] = q
+	}
+
+	var (
+		best  Encoding
+		bestQ float64
+	)
+	for _, encoding := range encodings {
+		q, ok := values[encoding.Name]
+		if !ok {
+			q = values["*"]
+		}
+
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best, bestQ > 0
+}
+
+// compressWriter holds the status and up to minCompressSize bytes back,
+// then decides whether to compress the body
+type compressWriter struct {
+	http.ResponseWriter
+	encoding Encoding
+	status   int
+	started  bool
+	// body held back until the decision
+	buf []byte
+	// compressor of the body (nil if it's sent as is)
+	encoder io.WriteCloser
+}
+
+// Func WriteHeader
+func (cw *compressWriter) WriteHeader(code int) {
+	// informational responses come before the final one
+	if code < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	if cw.status == 0 {
+		cw.status = code
+	}
+}
+
+// Func Write
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.started {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < minCompressSize {
+			return len(p), nil
+		}
+
+		if err := cw.start(false); err != nil {
+			return 0, err
+		}
+
+		return len(p), nil
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Write(p)
+	}
+
+	return cw.ResponseWriter.Write(p)
+}
+
+// Func Flush - flush the compressed data written so far
+func (cw *compressWriter) Flush() {
+	if !cw.started {
+		_ = cw.start(false)
+	}
+
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Func Unwrap
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Func start - decide on the compression, send the header and the body
+// held back; complete means the held back body is the whole one
+func (cw *compressWriter) start(complete bool) error {
+	cw.started = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	// the type net/http would sniff, it can't once the body is compressed
+	header := cw.Header()
+	if _, ok := header["Content-Type"]; !ok && len(cw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if !(complete && len(cw.buf) < minCompressSize) && cw.compressible() {
+		header.Del("Content-Length")
+		// ranges would refer to the compressed body
+		header.Del("Accept-Ranges")
+		header.Set("Content-Encoding", cw.encoding.Name)
+		// the compressed body differs from the identity one byte for byte
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		cw.encoder = cw.encoding.NewWriter(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+
+	return err
+}
+
+// Func compressible - whether the response should be compressed
+func (cw *compressWriter) compressible() bool {
+	switch cw.status {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		return false
+	}
+
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < minCompressSize {
+		return false
+	}
+
+	return compressibleType(header.Get("Content-Type"))
+}
+
+// Func compressibleType - media types which compress well
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml":
+		return true
+	}
+
+	return false
+}
+
+// Func close - finish the response (the compressed stream in particular)
+func (cw *compressWriter) close() {
+	if !cw.started {
+		_ = cw.start(true)
+	}
+
+	if cw.encoder != nil {
+		_ = cw.encoder.Close()
+	}
+}
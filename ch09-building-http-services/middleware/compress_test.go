@@ -0,0 +1,142 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"io"
+	"learn-network-programming/ch09-building-http-services/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Func TestCompress
+func TestCompress(t *testing.T) {
+	text := strings.Repeat("<p>Hello, friend!</p>\n", 100)
+
+	handler := middleware.Compress(
+		nil,
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/short":
+					_, _ = io.WriteString(w, "short")
+				case "/image":
+					w.Header().Set("Content-Type", "image/png")
+					_, _ = io.WriteString(w, text)
+				case "/empty":
+					w.WriteHeader(http.StatusNoContent)
+				case "/files/sage.svg":
+					http.ServeFile(w, r, "../files/sage.svg")
+				default:
+					// the type is sniffed before compressing
+					_, _ = io.WriteString(w, text)
+				}
+			},
+		),
+	)
+
+	testCases := []struct {
+		path     string
+		accept   string
+		encoding string
+	}{
+		// brotli is preferred on a tie
+		{"/", "gzip, deflate, br", "br"},
+		{"/", "br;q=1.0, gzip;q=0.5", "br"},
+		{"/", "br;q=0.5, gzip", "gzip"},
+		{"/", "*", "br"},
+		{"/", "gzip", "gzip"},
+		{"/", "br", "br"},
+		{"/", "gzip;q=0", ""},
+		{"/", "", ""},
+		{"/short", "gzip", ""},
+		{"/image", "gzip", ""},
+		{"/empty", "gzip", ""},
+		{"/files/sage.svg", "gzip", "gzip"},
+		{"/files/sage.svg", "br", "br"},
+	}
+
+	for i, testCase := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "http://test"+testCase.path, nil)
+		if testCase.accept != "" {
+			req.Header.Set("Accept-Encoding", testCase.accept)
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		resp := rec.Result()
+
+		if actual := resp.Header.Get("Content-Encoding"); actual != testCase.encoding {
+			t.Errorf("%d: expected encoding %q; actual %q", i, testCase.encoding, actual)
+			continue
+		}
+		if actual := resp.Header.Get("Vary"); actual != "Accept-Encoding" {
+			t.Errorf("%d: expected Vary %q; actual %q", i, "Accept-Encoding", actual)
+		}
+
+		if testCase.encoding == "" {
+			continue
+		}
+
+		if length := resp.Header.Get("Content-Length"); length != "" {
+			t.Errorf("%d: unexpected Content-Length %s", i, length)
+		}
+
+		var decoder io.Reader
+		switch testCase.encoding {
+		case "gzip":
+			zr, err := gzip.NewReader(resp.Body)
+			if err != nil {
+				t.Errorf("%d: %v", i, err)
+				continue
+			}
+			decoder = zr
+		case "br":
+			decoder = brotli.NewReader(resp.Body)
+		}
+		body, err := io.ReadAll(decoder)
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+		}
+
+		if testCase.path == "/" && string(body) != text {
+			t.Errorf("%d: unexpected body %q", i, body)
+		}
+		if actual := resp.Header.Get("Content-Type"); !strings.HasPrefix(actual, "text/html") &&
+			testCase.path == "/" {
+			t.Errorf("%d: expected sniffed text/html; actual %q", i, actual)
+		}
+	}
+}
+
+// Func TestCompressRange - partial responses aren't compressed
+func TestCompressRange(t *testing.T) {
+	handler := middleware.Compress(
+		nil,
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				http.ServeFile(w, r, "../files/sage.svg")
+			},
+		),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "http://test/sage.svg", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-9")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if actual := rec.Code; actual != http.StatusPartialContent {
+		t.Fatalf("expected %d; actual %d", http.StatusPartialContent, actual)
+	}
+	if actual := rec.Header().Get("Content-Encoding"); actual != "" {
+		t.Errorf("expected no encoding; actual %q", actual)
+	}
+	if actual := rec.Body.Len(); actual != 10 {
+		t.Errorf("expected 10 bytes; actual %d", actual)
+	}
+}
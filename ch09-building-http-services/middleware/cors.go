@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions lists what cross-origin requests may do
+type CORSOptions struct {
+	// Origins allowed to read responses, e.g., "https://example.com",
+	// or "*" for any (no cross-origin requests if empty)
+	AllowedOrigins []string
+	// Methods allowed in addition to the simple ones (GET, HEAD, POST)
+	AllowedMethods []string
+	// Request headers allowed in addition to the simple ones, or "*" for any
+	AllowedHeaders []string
+	// Response headers the client may read in addition to the simple ones
+	ExposedHeaders []string
+	// Whether cookies and credentials may be sent
+	AllowCredentials bool
+	// How long the client may cache a preflight response (not sent if zero)
+	MaxAge time.Duration
+}
+
+// Func CORS - add the Access-Control-* headers for the allowed origins
+// and answer their preflight requests. Requests of other origins pass
+// through unchanged, the browser then denies access to the response.
+func CORS(options CORSOptions, next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions &&
+				r.Header.Get("Access-Control-Request-Method") != ""
+
+			// the headers depend on the origin
+			w.Header().Add("Vary", "Origin")
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if origin == "" || !options.allowedOrigin(origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if preflight {
+				options.preflight(w, r, origin)
+				return
+			}
+
+			options.setOrigin(w.Header(), origin)
+			if len(options.ExposedHeaders) > 0 {
+				w.Header().Set(
+					"Access-Control-Expose-Headers",
+					strings.Join(options.ExposedHeaders, ", "),
+				)
+			}
+
+			next.ServeHTTP(w, r)
+		},
+	)
+}
+
+// Func preflight - reply to a preflight request, without the
+// Access-Control-* headers if the method or a header isn't allowed
+func (o CORSOptions) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	method := r.Header.Get("Access-Control-Request-Method")
+	requested := requestedHeaders(r.Header.Get("Access-Control-Request-Headers"))
+
+	if o.allowedMethod(method) && o.allowedHeaders(requested) {
+		header := w.Header()
+		o.setOrigin(header, origin)
+		header.Set("Access-Control-Allow-Methods", method)
+		if len(requested) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+		}
+		if o.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(o.MaxAge.Seconds())))
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Func setOrigin - allow the origin (and credentials if enabled)
+func (o CORSOptions) setOrigin(header http.Header, origin string) {
+	// credentials aren't allowed with the wildcard
+	if o.anyOrigin() && !o.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if o.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// Func anyOrigin - whether all origins are allowed
+func (o CORSOptions) anyOrigin() bool {
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Func allowedOrigin
+func (o CORSOptions) allowedOrigin(origin string) bool {
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Func allowedMethod
+func (o CORSOptions) allowedMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost:
+		return true
+	}
+
+	for _, allowed := range o.AllowedMethods {
+		if allowed == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Func allowedHeaders - whether all the headers are allowed
+func (o CORSOptions) allowedHeaders(headers []string) bool {
+	for _, header := range headers {
+		if !o.allowedHeader(header) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Func allowedHeader
+func (o CORSOptions) allowedHeader(header string) bool {
+	switch http.CanonicalHeaderKey(header) {
+	case "Accept", "Accept-Language", "Content-Language", "Content-Type":
+		return true
+	}
+
+	for _, allowed := range o.AllowedHeaders {
+		if allowed == "*" || strings.EqualFold(allowed, header) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Func requestedHeaders - the list in Access-Control-Request-Headers
+func requestedHeaders(value string) []string {
+	var headers []string
+	for _, header := range strings.Split(value, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+
+	return headers
+}
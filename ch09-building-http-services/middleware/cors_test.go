@@ -0,0 +1,117 @@
+package middleware_test
+
+import (
+	"learn-network-programming/ch09-building-http-services/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Func TestCORS
+func TestCORS(t *testing.T) {
+	handler := middleware.CORS(
+		middleware.CORSOptions{
+			AllowedOrigins:   []string{"https://app.example"},
+			AllowedMethods:   []string{http.MethodPut},
+			AllowedHeaders:   []string{"Authorization"},
+			ExposedHeaders:   []string{"X-Request-Id"},
+			AllowCredentials: true,
+			MaxAge:           time.Hour,
+		},
+		http.HandlerFunc(
+			func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+		),
+	)
+
+	testCases := []struct {
+		method        string
+		origin        string
+		requestMethod string
+		requestHeader string
+		code          int
+		allowOrigin   string
+		allowMethods  string
+		allowHeaders  string
+		expose        string
+	}{
+		// same-origin request
+		{http.MethodGet, "", "", "", http.StatusOK, "", "", "", ""},
+		// allowed origin
+		{http.MethodGet, "https://app.example", "", "", http.StatusOK, "https://app.example", "", "", "X-Request-Id"},
+		// other origin
+		{http.MethodGet, "https://evil.example", "", "", http.StatusOK, "", "", "", ""},
+		// preflight requests
+		{http.MethodOptions, "https://app.example", http.MethodPut, "authorization, content-type", http.StatusNoContent,
+			"https://app.example", http.MethodPut, "authorization, content-type", ""},
+		{http.MethodOptions, "https://app.example", http.MethodDelete, "", http.StatusNoContent, "", "", "", ""},
+		{http.MethodOptions, "https://app.example", http.MethodPut, "X-Secret", http.StatusNoContent, "", "", "", ""},
+		// OPTIONS without preflight goes to the handler
+		{http.MethodOptions, "https://app.example", "", "", http.StatusOK, "https://app.example", "", "", "X-Request-Id"},
+	}
+
+	for i, testCase := range testCases {
+		req := httptest.NewRequest(testCase.method, "http://test/", nil)
+		if testCase.origin != "" {
+			req.Header.Set("Origin", testCase.origin)
+		}
+		if testCase.requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", testCase.requestMethod)
+		}
+		if testCase.requestHeader != "" {
+			req.Header.Set("Access-Control-Request-Headers", testCase.requestHeader)
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		header := rec.Header()
+
+		if actual := rec.Code; actual != testCase.code {
+			t.Errorf("%d: expected %d; actual %d", i, testCase.code, actual)
+		}
+		if actual := header.Get("Access-Control-Allow-Origin"); actual != testCase.allowOrigin {
+			t.Errorf("%d: expected origin %q; actual %q", i, testCase.allowOrigin, actual)
+		}
+		if actual := header.Get("Access-Control-Allow-Methods"); actual != testCase.allowMethods {
+			t.Errorf("%d: expected methods %q; actual %q", i, testCase.allowMethods, actual)
+		}
+		if actual := header.Get("Access-Control-Allow-Headers"); actual != testCase.allowHeaders {
+			t.Errorf("%d: expected headers %q; actual %q", i, testCase.allowHeaders, actual)
+		}
+		if actual := header.Get("Access-Control-Expose-Headers"); actual != testCase.expose {
+			t.Errorf("%d: expected exposed headers %q; actual %q", i, testCase.expose, actual)
+		}
+
+		credentials := header.Get("Access-Control-Allow-Credentials")
+		if expected := testCase.allowOrigin != ""; (credentials == "true") != expected {
+			t.Errorf("%d: unexpected credentials %q", i, credentials)
+		}
+	}
+}
+
+// Func TestCORSWildcard - any origin gets "*" unless credentials are allowed
+func TestCORSWildcard(t *testing.T) {
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	for _, credentials := range []bool{false, true} {
+		handler := middleware.CORS(
+			middleware.CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: credentials},
+			next,
+		)
+
+		req := httptest.NewRequest(http.MethodGet, "http://test/", nil)
+		req.Header.Set("Origin", "https://any.example")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		expected := "*"
+		if credentials {
+			expected = "https://any.example"
+		}
+		if actual := rec.Header().Get("Access-Control-Allow-Origin"); actual != expected {
+			t.Errorf("credentials %t: expected %q; actual %q", credentials, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+)
+
+// Limit of the request body bytes drained after the handler, a connection
+// with a longer body left is closed by the server instead of being reused
+const maxDrain = 256 << 10
+
+// Func DrainAndClose (middleware handler for draining and closing
+// request body after the request is served)
+func DrainAndClose(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			// pass to the handler
+			next.ServeHTTP(w, r)
+			// drain and close the body
+			_, _ = io.CopyN(io.Discard, r.Body, maxDrain)
+			_ = r.Body.Close()
+		},
+	)
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import "net/http"
+
+// Func LimitBody - reject request bodies longer than limit bytes: with 413
+// right away if Content-Length says so, otherwise reading the body fails
+// with *http.MaxBytesError once the limit is exceeded
+func LimitBody(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				http.Error(
+					w,
+					http.StatusText(http.StatusRequestEntityTooLarge),
+					http.StatusRequestEntityTooLarge,
+				)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+			next.ServeHTTP(w, r)
+		},
+	)
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// Func Recover - log panics of the handler with the stack trace
+// (and the request ID if any) and reply with 500 instead. If the
+// response has already started, the connection is aborted so that
+// the client can't take the partial response for a complete one.
+// A nil logger means log.Default().
+func Recover(logger *log.Logger, next http.Handler) http.Handler {
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			rw := &recordingWriter{ResponseWriter: w}
+
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+
+				// the handler asked for the connection to be aborted
+				if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(v)
+				}
+
+				logger.Printf(
+					"panic serving %s %s (request %q): %v\n%s",
+					r.Method,
+					r.URL.Path,
+					RequestIDFromContext(r.Context()),
+					v,
+					debug.Stack(),
+				)
+
+				if rw.wroteHeader {
+					panic(http.ErrAbortHandler)
+				}
+
+				http.Error(
+					w,
+					http.StatusText(http.StatusInternalServerError),
+					http.StatusInternalServerError,
+				)
+			}()
+
+			next.ServeHTTP(wrap(rw, w), r)
+		},
+	)
+}
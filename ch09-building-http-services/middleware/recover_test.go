@@ -0,0 +1,157 @@
+package middleware_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"learn-network-programming/ch09-building-http-services/middleware"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Func TestRecover
+func TestRecover(t *testing.T) {
+	var logs bytes.Buffer
+
+	handler := middleware.RequestID(
+		middleware.Recover(
+			log.New(&logs, "", 0),
+			http.HandlerFunc(
+				func(http.ResponseWriter, *http.Request) {
+					panic("boom")
+				},
+			),
+		),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "http://test/panic", nil)
+	req.Header.Set(middleware.RequestIDHeader, "abc-123")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if actual := rec.Code; actual != http.StatusInternalServerError {
+		t.Errorf("expected %d; actual %d", http.StatusInternalServerError, actual)
+	}
+	if actual := rec.Header().Get(middleware.RequestIDHeader); actual != "abc-123" {
+		t.Errorf("expected request ID %q; actual %q", "abc-123", actual)
+	}
+
+	for _, expected := range []string{"GET /panic", `"abc-123"`, "boom", "goroutine"} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("expected %q in the log: %s", expected, logs.String())
+		}
+	}
+}
+
+// Func TestRecoverStarted - a panic after the response has started aborts it
+func TestRecoverStarted(t *testing.T) {
+	handler := middleware.Recover(
+		log.New(io.Discard, "", 0),
+		middleware.Compress(
+			nil,
+			http.HandlerFunc(
+				func(w http.ResponseWriter, _ *http.Request) {
+					w.Header().Set("Content-Type", "text/plain")
+					_, _ = io.WriteString(w, strings.Repeat("partial ", 1000))
+					panic("boom")
+				},
+			),
+		),
+	)
+
+	defer func() {
+		v := recover()
+		if err, ok := v.(error); !ok || !errors.Is(err, http.ErrAbortHandler) {
+			t.Errorf("expected %v; actual %v", http.ErrAbortHandler, v)
+		}
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "http://test/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+// Func TestRecoverPush - wrapped writers keep server push of the original one
+func TestRecoverPush(t *testing.T) {
+	var pushed bool
+
+	handler := middleware.Recover(
+		nil,
+		middleware.Compress(
+			nil,
+			http.HandlerFunc(
+				func(w http.ResponseWriter, _ *http.Request) {
+					_, pushed = w.(http.Pusher)
+				},
+			),
+		),
+	)
+
+	// the recorder doesn't push
+	req := httptest.NewRequest(http.MethodGet, "http://test/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if pushed {
+		t.Error("expected no pusher")
+	}
+
+	handler.ServeHTTP(pushRecorder{httptest.NewRecorder()}, req)
+	if !pushed {
+		t.Error("expected a pusher")
+	}
+}
+
+// pushRecorder is a recorder supporting server push
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+// Func Push
+func (pushRecorder) Push(string, *http.PushOptions) error {
+	return nil
+}
+
+// Func TestRequestID
+func TestRequestID(t *testing.T) {
+	var fromContext string
+
+	handler := middleware.RequestID(
+		http.HandlerFunc(
+			func(_ http.ResponseWriter, r *http.Request) {
+				fromContext = middleware.RequestIDFromContext(r.Context())
+			},
+		),
+	)
+
+	testCases := []struct {
+		id   string
+		kept bool
+	}{
+		{"", false},
+		{"req-1:a.b_c", true},
+		{"bad id\n", false},
+		{strings.Repeat("x", 200), false},
+	}
+
+	for i, testCase := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "http://test/", nil)
+		if testCase.id != "" {
+			req.Header.Set(middleware.RequestIDHeader, testCase.id)
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		actual := rec.Header().Get(middleware.RequestIDHeader)
+
+		if actual == "" || actual != fromContext {
+			t.Errorf("%d: expected the same ID; actual %q and %q", i, actual, fromContext)
+		}
+		if (actual == testCase.id) != testCase.kept {
+			t.Errorf("%d: expected kept %t; actual ID %q", i, testCase.kept, actual)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-Id"
+
+// Longest request ID accepted from the client
+const maxRequestID = 128
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// Func RequestID - give every request an ID: the one in the
+// X-Request-Id header (e.g., set by a proxy) if it's valid,
+// a random one otherwise. The ID is echoed in the response
+// and kept in the request context (RequestIDFromContext).
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		},
+	)
+}
+
+// Func RequestIDFromContext - ID set by RequestID ("" if none)
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Func newRequestID - 128 random bits in hex
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// Func validRequestID - not empty, not too long and safe to log
+// (letters, digits and "-", "_", ".", ":")
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestID {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,19 +1,28 @@
 package middleware
 
 import (
+	"learn-network-programming/ch09-building-http-services/restrict"
 	"net/http"
-	"path"
-	"strings"
 )
 
+// Func RestrictPrefix - reply with 404 to paths with a segment
+// starting with the prefix (e.g., "." for hidden files)
 func RestrictPrefix(prefix string, next http.Handler) http.Handler {
+	return Restrict(
+		restrict.MustCompile(restrict.Rules{Prefixes: []string{prefix}}),
+		next,
+	)
+}
+
+// Func Restrict - reply to the paths restricted by the rules
+// with their status instead of passing them to the handler
+func Restrict(rules *restrict.Matcher, next http.Handler) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
-			for _, p := range strings.Split(path.Clean(r.URL.Path), "/") {
-				if strings.HasPrefix(p, prefix) {
-					http.Error(w, "Not Found", http.StatusNotFound)
-					return
-				}
+			if _, restricted := rules.Match(r.URL.Path); restricted {
+				status := rules.Status()
+				http.Error(w, http.StatusText(status), status)
+				return
 			}
 
 			next.ServeHTTP(w, r)
@@ -2,6 +2,7 @@ package middleware_test
 
 import (
 	"learn-network-programming/ch09-building-http-services/middleware"
+	"learn-network-programming/ch09-building-http-services/restrict"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -52,3 +53,45 @@ func TestRestrictPrefix(t *testing.T) {
 		}
 	}
 }
+
+// Func TestRestrict - rule sets with a 403 reply
+func TestRestrict(t *testing.T) {
+	handler := middleware.Restrict(
+		restrict.MustCompile(
+			restrict.Rules{
+				Prefixes: []string{"."},
+				Globs:    []string{"*.bak"},
+				Allow:    []string{"/.well-known/"},
+				Status:   http.StatusForbidden,
+			},
+		),
+		http.HandlerFunc(
+			func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+		),
+	)
+
+	testCases := []struct {
+		path string
+		code int
+	}{
+		{"http://test/index.html", http.StatusNoContent},
+		{"http://test/.env", http.StatusForbidden},
+		{"http://test/index.html.bak", http.StatusForbidden},
+		// percent-encoded dots are decoded before matching
+		{"http://test/%2egit/config", http.StatusForbidden},
+		{"http://test/.well-known/security.txt", http.StatusNoContent},
+	}
+
+	for i, testCase := range testCases {
+		req := httptest.NewRequest(http.MethodGet, testCase.path, nil)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if actual := rec.Code; actual != testCase.code {
+			t.Errorf("%d: expected %d, actual %d", i, testCase.code, actual)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SecurityOptions are the security headers of the responses,
+// the ones left empty aren't sent
+type SecurityOptions struct {
+	// Max-age of Strict-Transport-Security, sent over TLS only
+	// (browsers ignore it over plain HTTP)
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// Content-Security-Policy, e.g., "default-src 'self'"
+	ContentSecurityPolicy string
+	// X-Frame-Options, e.g., "DENY"
+	FrameOptions string
+	// Referrer-Policy, e.g., "no-referrer"
+	ReferrerPolicy string
+}
+
+// Func SecurityHeaders - set the security headers before the handler
+// (which may still change them) and X-Content-Type-Options: nosniff
+// so that browsers keep to the declared content types
+func SecurityHeaders(options SecurityOptions, next http.Handler) http.Handler {
+	hsts := ""
+	if options.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(options.HSTSMaxAge.Seconds()))
+		if options.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if options.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Set("X-Content-Type-Options", "nosniff")
+
+			if hsts != "" && r.TLS != nil {
+				header.Set("Strict-Transport-Security", hsts)
+			}
+			if options.ContentSecurityPolicy != "" {
+				header.Set("Content-Security-Policy", options.ContentSecurityPolicy)
+			}
+			if options.FrameOptions != "" {
+				header.Set("X-Frame-Options", options.FrameOptions)
+			}
+			if options.ReferrerPolicy != "" {
+				header.Set("Referrer-Policy", options.ReferrerPolicy)
+			}
+
+			next.ServeHTTP(w, r)
+		},
+	)
+}
@@ -0,0 +1,102 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"learn-network-programming/ch09-building-http-services/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Func TestSecurityHeaders
+func TestSecurityHeaders(t *testing.T) {
+	handler := middleware.SecurityHeaders(
+		middleware.SecurityOptions{
+			HSTSMaxAge:            365 * 24 * time.Hour,
+			HSTSIncludeSubdomains: true,
+			ContentSecurityPolicy: "default-src 'self'",
+			FrameOptions:          "DENY",
+		},
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+	)
+
+	for _, secure := range []bool{false, true} {
+		req := httptest.NewRequest(http.MethodGet, "http://test/", nil)
+		if secure {
+			req.TLS = &tls.ConnectionState{}
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		header := rec.Header()
+
+		expected := map[string]string{
+			"X-Content-Type-Options":  "nosniff",
+			"Content-Security-Policy": "default-src 'self'",
+			"X-Frame-Options":         "DENY",
+			"Referrer-Policy":         "",
+			// HSTS only over TLS
+			"Strict-Transport-Security": "",
+		}
+		if secure {
+			expected["Strict-Transport-Security"] = "max-age=31536000; includeSubDomains"
+		}
+
+		for name, value := range expected {
+			if actual := header.Get(name); actual != value {
+				t.Errorf("TLS %t: expected %s %q; actual %q", secure, name, value, actual)
+			}
+		}
+	}
+}
+
+// Func TestLimitBody
+func TestLimitBody(t *testing.T) {
+	handler := middleware.LimitBody(
+		10,
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				_, err := io.ReadAll(r.Body)
+
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					http.Error(w, "too long", http.StatusRequestEntityTooLarge)
+					return
+				}
+
+				w.WriteHeader(http.StatusNoContent)
+			},
+		),
+	)
+
+	testCases := []struct {
+		body   string
+		length bool
+		code   int
+	}{
+		{"short", true, http.StatusNoContent},
+		{"0123456789", true, http.StatusNoContent},
+		// rejected by Content-Length
+		{"0123456789a", true, http.StatusRequestEntityTooLarge},
+		// rejected while reading
+		{"0123456789a", false, http.StatusRequestEntityTooLarge},
+	}
+
+	for i, testCase := range testCases {
+		req := httptest.NewRequest(http.MethodPost, "http://test/", strings.NewReader(testCase.body))
+		if !testCase.length {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if actual := rec.Code; actual != testCase.code {
+			t.Errorf("%d: expected %d; actual %d", i, testCase.code, actual)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import "net/http"
+
+// wrapper is a response writer of a middleware which keeps
+// flushing and access to the original writer (http.ResponseController)
+type wrapper interface {
+	http.ResponseWriter
+	http.Flusher
+	Unwrap() http.ResponseWriter
+}
+
+// pushWrapper adds server push to a wrapper
+type pushWrapper struct {
+	wrapper
+	http.Pusher
+}
+
+// Func wrap - the wrapper of the original writer, able to push
+// only if the original one is (pushes go to the original writer)
+func wrap(w wrapper, original http.ResponseWriter) http.ResponseWriter {
+	if pusher, ok := original.(http.Pusher); ok {
+		return pushWrapper{wrapper: w, Pusher: pusher}
+	}
+
+	return w
+}
+
+// recordingWriter records whether the response has started
+type recordingWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// Func WriteHeader
+func (rw *recordingWriter) WriteHeader(code int) {
+	// informational responses come before the final one
+	if code >= http.StatusOK {
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Func Write
+func (rw *recordingWriter) Write(p []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(p)
+}
+
+// Func Flush
+func (rw *recordingWriter) Flush() {
+	rw.wroteHeader = true
+	_ = http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Func Unwrap
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -3,25 +3,12 @@ package multiplexers
 import (
 	"fmt"
 	"io"
+	"learn-network-programming/ch09-building-http-services/middleware"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 )
 
-// func drainAndClose (middleware handler for draining and closing
-// request body after the request is served)
-func drainAndClose(next http.Handler) http.Handler {
-	return http.HandlerFunc(
-		func(w http.ResponseWriter, r *http.Request) {
-			// pass to the handler
-			next.ServeHTTP(w, r)
-			// drain and close the body
-			_, _ = io.Copy(io.Discard, r.Body)
-			_ = r.Body.Close()
-		},
-	)
-}
-
 // func TestSimpleMux
 func TestSimpleMux(t *testing.T) {
 	// create a new multiplexer
@@ -52,7 +39,7 @@ func TestSimpleMux(t *testing.T) {
 	)
 
 	// wrap the multiplexer handler in the drain and close handler
-	mux := drainAndClose(serveMux)
+	mux := middleware.DrainAndClose(serveMux)
 
 	testCases := []struct {
 		path     string
@@ -0,0 +1,147 @@
+// Package restrict matches request paths against rules of restricted
+// names, shared by the net/http middleware and the Caddy module.
+package restrict
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Rules lists the restricted paths. A path is restricted if one of its
+// segments has one of the prefixes or matches one of the globs, or if
+// the whole path matches one of the regular expressions, unless it's
+// within one of the allowed paths. Paths are cleaned before matching.
+type Rules struct {
+	// Prefixes of restricted segments, e.g., "." for hidden files
+	Prefixes []string `json:"prefixes,omitempty"`
+	// Patterns of restricted segments (path.Match), e.g., "*.bak" or "node_modules"
+	Globs []string `json:"globs,omitempty"`
+	// Regular expressions of restricted paths, e.g., `^/admin(/|$)`
+	Regexps []string `json:"regexps,omitempty"`
+	// Paths exempt from the rules including everything below them,
+	// e.g., "/.well-known/"
+	Allow []string `json:"allow,omitempty"`
+	// Status of the reply to restricted paths: 404 (if not set) hides
+	// that they exist, 403 tells that they're forbidden
+	Status int `json:"status,omitempty"`
+}
+
+// Matcher is the compiled form of Rules
+type Matcher struct {
+	prefixes []string
+	globs    []string
+	regexps  []*regexp.Regexp
+	allow    []string
+	status   int
+}
+
+// Func Compile - check and compile the rules
+func Compile(rules Rules) (*Matcher, error) {
+	m := &Matcher{
+		status: rules.Status,
+	}
+
+	if m.status == 0 {
+		m.status = http.StatusNotFound
+	}
+	if m.status < 400 || m.status > 499 {
+		return nil, fmt.Errorf("status %d isn't a client error", m.status)
+	}
+
+	for _, prefix := range rules.Prefixes {
+		if prefix == "" {
+			return nil, fmt.Errorf("empty prefix would restrict all paths")
+		}
+		m.prefixes = append(m.prefixes, prefix)
+	}
+
+	for _, glob := range rules.Globs {
+		if strings.Contains(glob, "/") {
+			return nil, fmt.Errorf("glob %q: matches a single segment, no slashes", glob)
+		}
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("glob %q: %w", glob, err)
+		}
+		m.globs = append(m.globs, glob)
+	}
+
+	for _, expr := range rules.Regexps {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("regexp %q: %w", expr, err)
+		}
+		m.regexps = append(m.regexps, re)
+	}
+
+	for _, allowed := range rules.Allow {
+		// compare on whole segments: "/.well-known" and "/.well-known/"
+		// both allow "/.well-known/acme" but not "/.well-knownx"
+		allowed = strings.TrimSuffix(clean(allowed), "/")
+		m.allow = append(m.allow, allowed)
+	}
+
+	return m, nil
+}
+
+// Func MustCompile - Compile panicking on errors, for rules known to be valid
+func MustCompile(rules Rules) *Matcher {
+	m, err := Compile(rules)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+// Func Match - the rule restricting the path (e.g., `glob "*.bak"`),
+// false if the path isn't restricted
+func (m *Matcher) Match(urlPath string) (string, bool) {
+	p := clean(urlPath)
+
+	for _, allowed := range m.allow {
+		if allowed == "" || p == allowed || strings.HasPrefix(p, allowed+"/") {
+			return "", false
+		}
+	}
+
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "" {
+			continue
+		}
+
+		for _, prefix := range m.prefixes {
+			if strings.HasPrefix(segment, prefix) {
+				return fmt.Sprintf("prefix %q", prefix), true
+			}
+		}
+
+		for _, glob := range m.globs {
+			// the patterns have been checked in Compile
+			if ok, _ := path.Match(glob, segment); ok {
+				return fmt.Sprintf("glob %q", glob), true
+			}
+		}
+	}
+
+	for _, re := range m.regexps {
+		if re.MatchString(p) {
+			return fmt.Sprintf("regexp %q", re), true
+		}
+	}
+
+	return "", false
+}
+
+// Func Status - status of the reply to restricted paths
+func (m *Matcher) Status() int {
+	return m.status
+}
+
+// Func clean - absolute path without "." and ".." segments, so that
+// "/.well-known/../.git" can't pass as allowed
+func clean(urlPath string) string {
+	return path.Clean("/" + urlPath)
+}
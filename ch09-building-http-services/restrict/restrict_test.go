@@ -0,0 +1,95 @@
+package restrict_test
+
+import (
+	"learn-network-programming/ch09-building-http-services/restrict"
+	"testing"
+)
+
+// Func TestMatch - paths against rule sets
+func TestMatch(t *testing.T) {
+	hidden := restrict.Rules{Prefixes: []string{"."}}
+	full := restrict.Rules{
+		Prefixes: []string{".", "_"},
+		Globs:    []string{"*.bak", "*~", "node_modules"},
+		Regexps:  []string{`^/admin(/|$)`, `\.(sql|env)$`},
+		Allow:    []string{"/.well-known/"},
+	}
+
+	testCases := []struct {
+		rules      restrict.Rules
+		path       string
+		restricted bool
+		rule       string
+	}{
+		{hidden, "/index.html", false, ""},
+		{hidden, "/.git/config", true, `prefix "."`},
+		{hidden, "/dir/.secret", true, `prefix "."`},
+		{hidden, "sage.svg", false, ""},
+		{hidden, ".secret", true, `prefix "."`},
+		// cleaned before matching
+		{hidden, "/a/./b/../c", false, ""},
+		{hidden, "/.well-known/acme", true, `prefix "."`},
+
+		{full, "/index.html", false, ""},
+		{full, "/_drafts/post.md", true, `prefix "_"`},
+		{full, "/index.html.bak", true, `glob "*.bak"`},
+		{full, "/notes.txt~", true, `glob "*~"`},
+		{full, "/app/node_modules/x/index.js", true, `glob "node_modules"`},
+		{full, "/node_modules_readme", false, ""},
+		{full, "/admin", true, "regexp \"^/admin(/|$)\""},
+		{full, "/admin/users", true, "regexp \"^/admin(/|$)\""},
+		{full, "/administrator", false, ""},
+		{full, "/backup/db.sql", true, "regexp \"\\\\.(sql|env)$\""},
+		// allowed paths
+		{full, "/.well-known/acme-challenge/token", false, ""},
+		{full, "/.well-known", false, ""},
+		{full, "/.well-known/x.bak", false, ""},
+		{full, "/.well-knownx", true, `prefix "."`},
+		{full, "/.well-known/../.git/config", true, `prefix "."`},
+	}
+
+	for i, testCase := range testCases {
+		m, err := restrict.Compile(testCase.rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rule, restricted := m.Match(testCase.path)
+		if restricted != testCase.restricted {
+			t.Errorf("%d: %s: expected restricted %t; actual %t", i, testCase.path, testCase.restricted, restricted)
+		}
+		if rule != testCase.rule {
+			t.Errorf("%d: %s: expected rule %s; actual %s", i, testCase.path, testCase.rule, rule)
+		}
+	}
+}
+
+// Func TestCompile - invalid rules and statuses
+func TestCompile(t *testing.T) {
+	testCases := []struct {
+		rules  restrict.Rules
+		status int
+		valid  bool
+	}{
+		{restrict.Rules{}, 404, true},
+		{restrict.Rules{Status: 403}, 403, true},
+		{restrict.Rules{Status: 200}, 0, false},
+		{restrict.Rules{Status: 500}, 0, false},
+		{restrict.Rules{Prefixes: []string{""}}, 0, false},
+		{restrict.Rules{Globs: []string{"[a-"}}, 0, false},
+		{restrict.Rules{Globs: []string{"dir/*.bak"}}, 0, false},
+		{restrict.Rules{Regexps: []string{"(unclosed"}}, 0, false},
+	}
+
+	for i, testCase := range testCases {
+		m, err := restrict.Compile(testCase.rules)
+		if (err == nil) != testCase.valid {
+			t.Errorf("%d: expected valid %t; actual error %v", i, testCase.valid, err)
+			continue
+		}
+
+		if err == nil && m.Status() != testCase.status {
+			t.Errorf("%d: expected status %d; actual %d", i, testCase.status, m.Status())
+		}
+	}
+}
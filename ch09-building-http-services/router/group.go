@@ -0,0 +1,46 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Group is a set of routes sharing a path prefix and middleware
+type Group struct {
+	router     *Router
+	prefix     string
+	middleware []Middleware
+}
+
+// Func Use - wrap the handlers registered in the group from now on
+// (and in its subgroups created from now on); the first middleware
+// added is the outermost one
+func (g *Group) Use(middleware ...Middleware) {
+	g.middleware = append(g.middleware, middleware...)
+}
+
+// Func Group - subgroup with the prefix appended (e.g., "/api")
+// and the middleware of this group
+func (g *Group) Group(prefix string) *Group {
+	return &Group{
+		router:     g.router,
+		prefix:     g.prefix + strings.TrimSuffix(prefix, "/"),
+		middleware: append([]Middleware(nil), g.middleware...),
+	}
+}
+
+// Func Handle - register the handler for the method and the pattern
+// (relative to the group's prefix); panics on conflicting patterns
+// like http.ServeMux does
+func (g *Group) Handle(method, pattern string, handler http.Handler) {
+	for i := len(g.middleware) - 1; i >= 0; i-- {
+		handler = g.middleware[i](handler)
+	}
+
+	g.router.handle(method, g.prefix+pattern, handler)
+}
+
+// Func HandleFunc - register the function for the method and the pattern
+func (g *Group) HandleFunc(method, pattern string, f func(http.ResponseWriter, *http.Request)) {
+	g.Handle(method, pattern, http.HandlerFunc(f))
+}
@@ -0,0 +1,121 @@
+// Package router dispatches requests by path pattern and then by method.
+//
+// Patterns are those of http.ServeMux without the method: "/users/{id}",
+// "/files/{path...}", "/static/" (a prefix) or "/{$}" (the root only);
+// path parameters are read with http.Request.PathValue. All methods of
+// a pattern form a handlers.Methods, so requests get the "Allow" header
+// with 405 and answers to OPTIONS like with handlers.Methods alone.
+package router
+
+import (
+	"fmt"
+	"learn-network-programming/ch09-building-http-services/handlers"
+	"net/http"
+	"sync"
+)
+
+// Middleware wraps a handler with extra behavior
+type Middleware func(next http.Handler) http.Handler
+
+// Router is an http.Handler with routes registered directly or in groups
+type Router struct {
+	// Handler for paths matching no pattern (http.NotFound if not set)
+	NotFound http.Handler
+	// Handler for methods a pattern has no handler for, the "Allow" header
+	// is already set (405 with handlers.Methods' message if not set)
+	MethodNotAllowed http.Handler
+
+	mux *http.ServeMux
+	// Group without prefix
+	root *Group
+	// Methods by pattern
+	routes   map[string]handlers.Methods
+	fallback sync.Once
+}
+
+// Func New - router without routes
+func New() *Router {
+	r := &Router{
+		mux:    http.NewServeMux(),
+		routes: make(map[string]handlers.Methods),
+	}
+	r.root = &Group{router: r}
+
+	return r
+}
+
+// Func Use - wrap the handlers registered from now on
+func (r *Router) Use(middleware ...Middleware) {
+	r.root.Use(middleware...)
+}
+
+// Func Group - group of routes with the prefix (e.g., "/api")
+// and the router's middleware
+func (r *Router) Group(prefix string) *Group {
+	return r.root.Group(prefix)
+}
+
+// Func Handle - register the handler for the method and the pattern
+func (r *Router) Handle(method, pattern string, handler http.Handler) {
+	r.root.Handle(method, pattern, handler)
+}
+
+// Func HandleFunc - register the function for the method and the pattern
+func (r *Router) HandleFunc(method, pattern string, f func(http.ResponseWriter, *http.Request)) {
+	r.root.HandleFunc(method, pattern, f)
+}
+
+// Func ServeHTTP. Routes must be registered before serving starts;
+// the router's own middleware doesn't apply to NotFound and
+// MethodNotAllowed, wrap the router itself for that.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.fallback.Do(func() {
+		// Unmatched paths fall to "/" unless it's a route of its own
+		if _, ok := r.routes["/"]; !ok {
+			r.mux.Handle("/", http.HandlerFunc(r.notFound))
+		}
+	})
+
+	r.mux.ServeHTTP(w, req)
+}
+
+// Func handle - add the method's handler to the pattern's methods,
+// panics if it has one already like http.ServeMux does
+func (r *Router) handle(method, pattern string, handler http.Handler) {
+	methods, ok := r.routes[pattern]
+	if !ok {
+		methods = make(handlers.Methods)
+		r.routes[pattern] = methods
+		r.mux.Handle(pattern, r.dispatch(methods))
+	}
+
+	if _, ok := methods[method]; ok {
+		panic(fmt.Sprintf("router: multiple registrations for %s %s", method, pattern))
+	}
+
+	methods[method] = handler
+}
+
+// Func dispatch - handler of a pattern's methods
+func (r *Router) dispatch(methods handlers.Methods) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, ok := methods[req.Method]
+		if ok || req.Method == http.MethodOptions || r.MethodNotAllowed == nil {
+			methods.ServeHTTP(w, req)
+			return
+		}
+
+		w.Header().Set("Allow", methods.Allowed())
+		r.MethodNotAllowed.ServeHTTP(w, req)
+	})
+}
+
+// Func notFound - reply with NotFound or the default 404
+func (r *Router) notFound(w http.ResponseWriter, req *http.Request) {
+	if r.NotFound != nil {
+		r.NotFound.ServeHTTP(w, req)
+		return
+	}
+
+	http.NotFound(w, req)
+}
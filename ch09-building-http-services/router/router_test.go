@@ -0,0 +1,185 @@
+package router_test
+
+import (
+	"io"
+	"learn-network-programming/ch09-building-http-services/router"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Func testRouter - router with a user resource in a group with a header middleware
+func testRouter(t *testing.T) *router.Router {
+	t.Helper()
+
+	r := router.New()
+
+	r.HandleFunc(http.MethodGet, "/{$}", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "index")
+	})
+
+	api := r.Group("/api/")
+	api.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-Api", "1")
+			next.ServeHTTP(w, req)
+		})
+	})
+
+	users := api.Group("/users")
+	users.HandleFunc(http.MethodGet, "/{id}", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.WriteString(w, "get "+req.PathValue("id"))
+	})
+	users.HandleFunc(http.MethodDelete, "/{id}", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.WriteString(w, "delete "+req.PathValue("id"))
+	})
+	users.HandleFunc(http.MethodGet, "/{id}/files/{path...}", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.WriteString(w, req.PathValue("id")+":"+req.PathValue("path"))
+	})
+
+	return r
+}
+
+// Func TestRouter
+func TestRouter(t *testing.T) {
+	r := testRouter(t)
+
+	testCases := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+		allow  string
+		api    string
+	}{
+		{http.MethodGet, "/", http.StatusOK, "index", "", ""},
+		{http.MethodGet, "/api/users/42", http.StatusOK, "get 42", "", "1"},
+		{http.MethodDelete, "/api/users/42", http.StatusOK, "delete 42", "", "1"},
+		{http.MethodGet, "/api/users/7/files/a/b.txt", http.StatusOK, "7:a/b.txt", "", "1"},
+		// methods without handlers
+		{http.MethodPost, "/api/users/42", http.StatusMethodNotAllowed, "Method not allowed\n", "DELETE, GET", ""},
+		{http.MethodOptions, "/api/users/42", http.StatusOK, "", "DELETE, GET", ""},
+		// paths without routes
+		{http.MethodGet, "/missing", http.StatusNotFound, "404 page not found\n", "", ""},
+		{http.MethodGet, "/api/users", http.StatusNotFound, "404 page not found\n", "", ""},
+	}
+
+	for i, testCase := range testCases {
+		req := httptest.NewRequest(testCase.method, "http://test"+testCase.path, nil)
+		rec := httptest.NewRecorder()
+
+		r.ServeHTTP(rec, req)
+		resp := rec.Result()
+
+		if actual := resp.StatusCode; actual != testCase.code {
+			t.Errorf("%d: expected %d; actual %d", i, testCase.code, actual)
+		}
+		if actual := rec.Body.String(); actual != testCase.body {
+			t.Errorf("%d: expected body %q; actual %q", i, testCase.body, actual)
+		}
+		if actual := resp.Header.Get("Allow"); actual != testCase.allow {
+			t.Errorf("%d: expected Allow %q; actual %q", i, testCase.allow, actual)
+		}
+		if actual := resp.Header.Get("X-Api"); actual != testCase.api {
+			t.Errorf("%d: expected X-Api %q; actual %q", i, testCase.api, actual)
+		}
+	}
+}
+
+// Func TestCustomHandlers
+func TestCustomHandlers(t *testing.T) {
+	r := testRouter(t)
+
+	r.NotFound = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "nothing here", http.StatusNotFound)
+	})
+	r.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "try "+w.Header().Get("Allow"), http.StatusMethodNotAllowed)
+	})
+
+	testCases := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/missing", http.StatusNotFound, "nothing here\n"},
+		{http.MethodPut, "/api/users/42", http.StatusMethodNotAllowed, "try DELETE, GET\n"},
+		// OPTIONS still lists the methods
+		{http.MethodOptions, "/api/users/42", http.StatusOK, ""},
+	}
+
+	for i, testCase := range testCases {
+		req := httptest.NewRequest(testCase.method, "http://test"+testCase.path, nil)
+		rec := httptest.NewRecorder()
+
+		r.ServeHTTP(rec, req)
+
+		if actual := rec.Code; actual != testCase.code {
+			t.Errorf("%d: expected %d; actual %d", i, testCase.code, actual)
+		}
+		if actual := rec.Body.String(); actual != testCase.body {
+			t.Errorf("%d: expected body %q; actual %q", i, testCase.body, actual)
+		}
+	}
+}
+
+// Func TestRootRoute - a "/" route catches the unmatched paths
+func TestRootRoute(t *testing.T) {
+	r := router.New()
+	r.HandleFunc(http.MethodGet, "/", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "root")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "http://test/anything", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if actual := rec.Body.String(); actual != "root" {
+		t.Errorf("expected %q; actual %q", "root", actual)
+	}
+}
+
+// Func TestMiddlewareOrder - the first middleware added is the outermost
+// and a subgroup keeps the middleware of its parent
+func TestMiddlewareOrder(t *testing.T) {
+	var order []string
+	record := func(name string) router.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, req)
+			})
+		}
+	}
+
+	r := router.New()
+	r.Use(record("a"), record("b"))
+	g := r.Group("/g")
+	g.Use(record("c"))
+	g.HandleFunc(http.MethodGet, "/x", func(http.ResponseWriter, *http.Request) {
+		order = append(order, "handler")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://test/g/x", nil))
+
+	expected := "a b c handler"
+	if actual := strings.Join(order, " "); actual != expected {
+		t.Errorf("expected %q; actual %q", expected, actual)
+	}
+}
+
+// Func TestDuplicate - registering a method of a pattern twice panics
+func TestDuplicate(t *testing.T) {
+	r := router.New()
+	r.HandleFunc(http.MethodGet, "/a", func(http.ResponseWriter, *http.Request) {})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+
+	r.Group("/a").HandleFunc(http.MethodGet, "", func(http.ResponseWriter, *http.Request) {})
+}
@@ -2,27 +2,38 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"learn-network-programming/ch07-unix-domain-sockets/activation"
+	"learn-network-programming/ch09-building-http-services/config"
+	"learn-network-programming/ch09-building-http-services/graceful"
 	"learn-network-programming/ch09-building-http-services/handlers"
-	"learn-network-programming/ch09-building-http-services/middleware"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"reflect"
+	"syscall"
 	"time"
 )
 
 // CLI options block:
+// - configuration file (see server.toml), or
 // - listen addres,
 // - dir to serve,
 // - certificate,
-// - private key
+// - private key,
+// - shutdown timings
 var (
-	addr  = flag.String("listen", "127.0.0.1:8080", "listen address")
-	files = flag.String("files", "./files", "static file directory")
-	cert  = flag.String("cert", "", "certificate")
-	pkey  = flag.String("pkey", "", "private key")
+	configPath = flag.String("config", "", "configuration file (replaces the other options)")
+	addr       = flag.String("listen", "127.0.0.1:8080", "listen address")
+	files      = flag.String("files", "./files", "static file directory")
+	cert       = flag.String("cert", "", "certificate")
+	pkey       = flag.String("pkey", "", "private key")
+	readyDelay = flag.Duration("ready-delay", 0, "time between reporting not ready and shutting down")
+	drain      = flag.Duration("drain", config.DefaultDrainTimeout, "time the requests in flight have to finish")
 )
 
 // func main
@@ -30,8 +41,15 @@ func main() {
 	// parse CLI options
 	flag.Parse()
 
-	// run the application and log if error
-	err := run(*addr, *files, *cert, *pkey)
+	// load the configuration file or build the configuration from the options
+	cfg, err := loadConfig(*configPath, *addr, *files, *cert, *pkey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// run the application and log if error (exits with 1, also if
+	// requests had to be cut off on shutdown)
+	err = run(cfg, *configPath)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -40,25 +58,114 @@ func main() {
 	log.Println("Server gracefully shutdown")
 }
 
+// func loadConfig - the configuration file if there's one,
+// the configuration equivalent to the options otherwise
+func loadConfig(path, addr, files, cert, pkey string) (*config.Config, error) {
+	if path != "" {
+		return config.Load(path)
+	}
+
+	index := filepath.Join(files, "index.html")
+	cfg := &config.Config{
+		Listeners: []config.Listener{
+			{Address: addr, Socket: "*", Cert: cert, Key: pkey},
+		},
+		Middleware: config.Middleware{
+			RequestID:   true,
+			Recover:     true,
+			Compress:    true,
+			MaxBodySize: 1 << 20,
+			Security: &config.Security{
+				HSTSMaxAge:            365 * 24 * time.Hour,
+				ContentSecurityPolicy: "default-src 'self'",
+				FrameOptions:          "DENY",
+				ReferrerPolicy:        "no-referrer",
+			},
+		},
+		// serve non-hidden content from "files" directory at /static/,
+		// readable from pages of any origin
+		Static: []config.Static{
+			{Prefix: "/static/", Dir: files, CORSOrigins: []string{"*"}},
+		},
+		Pages: []config.Page{
+			// serve index.html at "/" and push the additional resources if possible
+			{
+				Path: "/",
+				File: index,
+				Push: []string{"/static/style.css", "/static/hiking.svg"},
+			},
+			// serve index.html without pushes at "/2"
+			{Path: "/2", File: index},
+		},
+		Shutdown: config.Shutdown{
+			ReadyDelay:    *readyDelay,
+			DrainTimeout:  *drain,
+			ReadinessPath: "/readyz",
+		},
+	}
+
+	return cfg, cfg.Validate()
+}
+
 // func run
-func run(addr, files, cert, pkey string) error {
+func run(cfg *config.Config, path string) error {
+	handler, err := cfg.Handler(nil)
+	if err != nil {
+		return err
+	}
+	// the handler replaced on configuration reloads
+	swap := handlers.NewSwap(handler)
+	// not ready until serving and again once shutting down
+	readiness := new(graceful.Readiness)
+
 	// create the server, specify idle timeout and read header timeout
 	server := http.Server{
-		Addr:              addr,
-		Handler:           buildHandler(files),
+		Handler:           readiness.Route(cfg.Shutdown.ReadinessPath, swap),
 		IdleTimeout:       time.Minute,
 		ReadHeaderTimeout: 30 * time.Second,
 	}
 
-	// channel to synchronize with the background signal listener
-	done := make(chan struct{})
+	// channel to receive the result of the shutdown from the signal listener
+	done := make(chan error, 1)
 	// run background signal listener
 	go func() {
-		listenToInterrupt(&server, done)
+		listenToInterrupt(&server, readiness, cfg.Shutdown, done)
 	}()
 
-	// report server start
-	log.Printf("Serving files in %q over %s\n", files, server.Addr)
+	// reload the configuration file on SIGHUP and whenever it changes
+	if path != "" {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go func() {
+			err := config.Watch(ctx, path, func() {
+				reload(path, cfg, swap)
+			}, nil)
+			if err != nil {
+				log.Printf("watch %s: %v", path, err)
+			}
+		}()
+	}
+
+	// open all the listeners before serving on any
+	listeners := make([]net.Listener, 0, len(cfg.Listeners))
+	for _, l := range cfg.Listeners {
+		listener, err := listen(l)
+		if err != nil {
+			for _, opened := range listeners {
+				_ = opened.Close()
+			}
+			return err
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	// tell the supervisor (if any) and the health checks that the server is ready
+	if _, err := activation.Notify(activation.Ready); err != nil {
+		log.Printf("notify: %v", err)
+	}
+	readiness.Set(true)
 
 	// helper function to filter relevant errors only
 	filterErrors := func(err error) error {
@@ -69,91 +176,122 @@ func run(addr, files, cert, pkey string) error {
 		return err
 	}
 
-	// if certificate and private key are specified, serve TLS
-	if cert != "" && pkey != "" {
-		log.Println("Serve TLS")
-		return filterErrors(server.ListenAndServeTLS(cert, pkey))
+	errs := make(chan error, len(listeners))
+	for i, listener := range listeners {
+		go func(l config.Listener) {
+			// if certificate and private key are specified, serve TLS
+			if l.Cert != "" && l.Key != "" {
+				log.Printf("Serving TLS over %s\n", listener.Addr())
+				errs <- filterErrors(server.ServeTLS(listener, l.Cert, l.Key))
+				return
+			}
+
+			log.Printf("Serving over %s\n", listener.Addr())
+			errs <- filterErrors(server.Serve(listener))
+		}(cfg.Listeners[i])
+	}
+
+	// stop serving on all the listeners if one fails
+	var errFirst error
+	for range listeners {
+		if err := <-errs; err != nil && errFirst == nil {
+			errFirst = err
+			_ = server.Close()
+		}
+	}
+
+	if errFirst != nil {
+		return errFirst
 	}
 
-	return filterErrors(server.ListenAndServe())
+	// serving stops as soon as the shutdown begins,
+	// wait for the requests in flight
+	return <-done
 }
 
-// func buildHandler
-func buildHandler(files string) http.Handler {
-	// create a new multiplexer
-	mux := http.NewServeMux()
-
-	// serve non-hidden content from "files" directory at /static/
-	mux.Handle(
-		"/static/",
-		http.StripPrefix(
-			"/static/",
-			middleware.RestrictPrefix(
-				".",
-				http.FileServer(http.Dir(files)),
-			),
-		),
-	)
-
-	// serve index.html at "/" and push the additional resources if possible
-	mux.Handle(
-		"/",
-		handlers.Methods{
-			http.MethodGet: http.HandlerFunc(
-				func(w http.ResponseWriter, r *http.Request) {
-					if pusher, ok := w.(http.Pusher); ok {
-						targets := []string{
-							"/static/style.css",
-							"/static/hiking.svg",
-						}
-
-						for _, target := range targets {
-							err := pusher.Push(target, nil)
-							if err != nil {
-								log.Printf("%s push failed: %v", target, err)
-							}
-						}
-					}
-
-					http.ServeFile(w, r, filepath.Join(files, "index.html"))
-				},
-			),
-		},
-	)
-
-	// serve index.html without pushes at "/2"
-	mux.Handle(
-		"/2",
-		handlers.Methods{
-			http.MethodGet: http.HandlerFunc(
-				func(w http.ResponseWriter, r *http.Request) {
-					http.ServeFile(w, r, filepath.Join(files, "index.html"))
-				},
-			),
-		},
-	)
+// func reload - load the configuration file and replace the handler,
+// keeping the running configuration if the new one is invalid
+func reload(path string, running *config.Config, swap *handlers.Swap) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		log.Printf("reload: %v", err)
+		return
+	}
+
+	handler, err := cfg.Handler(nil)
+	if err != nil {
+		log.Printf("reload: %v", err)
+		return
+	}
 
-	return mux
+	// connections in progress keep going, listeners stay as they are
+	swap.Store(handler)
+	log.Printf("reloaded %s", path)
+
+	if !reflect.DeepEqual(cfg.Listeners, running.Listeners) || cfg.Shutdown != running.Shutdown {
+		log.Println("reload: listener and shutdown changes take effect after a restart")
+	}
 }
 
-// func listenToInterrupt
-func listenToInterrupt(server *http.Server, done chan struct{}) {
-	// create a channel with capacity 1 (not to block notifier)
-	c := make(chan os.Signal, 1)
-	// register the channel to redirect SIG_INT
-	signal.Notify(c, os.Interrupt)
-
-	for {
-		// wait for os.Interrupt
-		if <-c == os.Interrupt {
-			// gracefully shutdown the server and check errors
-			if err := server.Shutdown(context.Background()); err != nil {
-				log.Printf("shutdown: %v", err)
-			}
+// func listen - the listener inherited with systemd socket activation
+// if there's one, a new TCP listener on the address otherwise
+func listen(l config.Listener) (net.Listener, error) {
+	if l.Socket != "" {
+		name := l.Socket
+		if name == "*" {
+			name = ""
+		}
 
-			// close the sync channel and exit
-			close(done)
-			return
+		listener, err := activation.Listener(name)
+		if err != nil {
+			return nil, err
 		}
+
+		if listener != nil {
+			return listener, nil
+		}
+	}
+
+	if l.Address == "" {
+		return nil, errors.New("socket " + l.Socket + " not passed and no address to listen on")
 	}
+
+	return net.Listen("tcp", l.Address)
+}
+
+// func listenToInterrupt - shut the server down on SIGINT or SIGTERM
+// and send the result to done; a second signal cuts the requests
+// in flight off right away
+func listenToInterrupt(
+	server *http.Server,
+	readiness *graceful.Readiness,
+	shutdown config.Shutdown,
+	done chan<- error,
+) {
+	// create a channel with capacity 2 (not to block notifier on the second signal)
+	c := make(chan os.Signal, 2)
+	// register the channel to redirect SIGINT and SIGTERM
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
+
+	sig := <-c
+	log.Printf("%v: shutting down", sig)
+
+	// tell the supervisor (if any) that the server is stopping
+	_, _ = activation.Notify(activation.Stopping)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-c:
+			log.Println("second signal: closing connections")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// report not ready, then drain the requests within the timeout
+	done <- graceful.Shutdown(ctx, server, readiness, shutdown.ReadyDelay, shutdown.DrainTimeout)
 }
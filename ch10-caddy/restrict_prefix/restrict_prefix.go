@@ -2,8 +2,8 @@ package restrictprefix
 
 import (
 	"fmt"
+	"learn-network-programming/ch09-building-http-services/restrict"
 	"net/http"
-	"strings"
 
 	"github.com/caddyserver/caddy/v2"
 	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
@@ -18,7 +18,13 @@ func init() {
 // struct RestrictPrefix
 type RestrictPrefix struct {
 	// prefix + annotations for json unmarschalling
+	// (added to the prefixes of the rules)
 	Prefix string `json:"prefix,omitempty"`
+	// prefixes, globs, regexps, allow and status, shared with the
+	// net/http middleware (inlined in json)
+	restrict.Rules
+	// rules compiled on Provision
+	matcher *restrict.Matcher
 	// logger passed from caddy
 	logger *zap.Logger
 }
@@ -33,8 +39,22 @@ func (RestrictPrefix) CaddyModule() caddy.ModuleInfo {
 
 // func Provision to do addional setup after loading
 func (p *RestrictPrefix) Provision(ctx caddy.Context) error {
-	// initialize prefix
-	p.Prefix = "."
+	// restrict hidden files if no rules are configured
+	rules := p.Rules
+	if p.Prefix != "" {
+		rules.Prefixes = append([]string{p.Prefix}, rules.Prefixes...)
+	}
+	if len(rules.Prefixes) == 0 && len(rules.Globs) == 0 && len(rules.Regexps) == 0 {
+		rules.Prefixes = []string{"."}
+	}
+
+	// compile the rules
+	matcher, err := restrict.Compile(rules)
+	if err != nil {
+		return err
+	}
+	p.matcher = matcher
+
 	// retrieve logger created by caddy and associaated with the module
 	p.logger = ctx.Logger(p)
 	return nil
@@ -42,9 +62,9 @@ func (p *RestrictPrefix) Provision(ctx caddy.Context) error {
 
 // func Validate to validate module state after Provision is called
 func (p *RestrictPrefix) Validate() error {
-	// check prefix
-	if p.Prefix == "" {
-		return fmt.Errorf("Prefix not initialized")
+	// check rules
+	if p.matcher == nil {
+		return fmt.Errorf("Rules not initialized")
 	}
 
 	// check logger
@@ -61,20 +81,18 @@ func (p RestrictPrefix) ServeHTTP(
 	r *http.Request,
 	next caddyhttp.Handler,
 ) error {
-	// iterate over the path
-	for _, part := range strings.Split(r.URL.Path, "/") {
-		// reply with 404 if it contains prefixed parts
-		if strings.HasPrefix(part, p.Prefix) {
-			http.Error(w, "Not Found", http.StatusNotFound)
-			// log that access was restricted
-			p.logger.Debug(
-				"access restricted by prefix",
-				zap.String("part", part),
-				zap.String("path", r.URL.Path),
-			)
+	// reply with the configured status if the path is restricted
+	if rule, restricted := p.matcher.Match(r.URL.Path); restricted {
+		status := p.matcher.Status()
+		http.Error(w, http.StatusText(status), status)
+		// log that access was restricted
+		p.logger.Debug(
+			"access restricted",
+			zap.String("rule", rule),
+			zap.String("path", r.URL.Path),
+		)
 
-			return nil
-		}
+		return nil
 	}
 
 	// pass execution to the next handler
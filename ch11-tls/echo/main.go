@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"learn-network-programming/ch07-unix-domain-sockets/activation"
+	ch11 "learn-network-programming/ch11-tls"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// CLI options block:
+// - listen address (unless systemd passes a listener),
+// - certificate,
+// - private key,
+// - idle timeout
+var (
+	addr = flag.String("listen", "localhost:4043", "listen address")
+	cert = flag.String("cert", "cert.pem", "certificate")
+	pkey = flag.String("pkey", "key.pem", "private key")
+	idle = flag.Duration("idle", time.Minute, "idle timeout")
+)
+
+// func main
+func main() {
+	// parse CLI options
+	flag.Parse()
+
+	// stop on interrupt or termination
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// run the server and log if error
+	if err := run(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Server stopped")
+}
+
+// func run
+func run(ctx context.Context) error {
+	// use the listener passed by the supervisor or listen on the address
+	listener, err := activation.Listener("")
+	if err != nil {
+		return err
+	}
+
+	if listener == nil {
+		listener, err = net.Listen("tcp", *addr)
+		if err != nil {
+			return err
+		}
+	}
+
+	// close the listener on stop
+	go func() {
+		<-ctx.Done()
+		_, _ = activation.Notify(activation.Stopping)
+		_ = listener.Close()
+	}()
+
+	// report server start, tell the supervisor (if any) we're ready
+	log.Printf("Serving TLS echo over %s\n", listener.Addr())
+	if _, err := activation.Notify(activation.Ready); err != nil {
+		log.Printf("notify: %v", err)
+	}
+
+	server := ch11.NewTLSServer(ctx, *addr, *idle, nil)
+	err = server.ServeTLS(listener, *cert, *pkey)
+
+	// accept fails once the listener is closed on stop
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,69 @@
+// Package activation is the part of the systemd socket activation protocol
+// the gRPC server needs: the inherited listener and readiness notifications.
+// This module can't import the root one (they share the module path),
+// see ch07-unix-domain-sockets/activation for the complete package.
+package activation
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Inherited descriptors start after stdin, stdout and stderr
+const listenFdsStart = 3
+
+// Notification states
+const (
+	// The service finished starting up
+	Ready = "READY=1"
+	// The service is shutting down
+	Stopping = "STOPPING=1"
+)
+
+// func Listener - the first listener passed by the supervisor,
+// nil if the process isn't socket activated
+func Listener() (net.Listener, error) {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	if pid == "" || fds == "" || pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+
+	// don't pass the sockets to child processes
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("activation: invalid LISTEN_FDS %q", fds)
+	}
+
+	f := os.NewFile(uintptr(listenFdsStart), "LISTEN_FD_3")
+	defer func() { _ = f.Close() }()
+
+	return net.FileListener(f)
+}
+
+// func Notify - send the states to the supervisor,
+// false if the process isn't supervised (NOTIFY_SOCKET isn't set)
+func Notify(states ...string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("activation: notify: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return false, fmt.Errorf("activation: notify: %w", err)
+	}
+
+	return true, nil
+}
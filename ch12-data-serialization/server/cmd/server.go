@@ -2,10 +2,14 @@ package main
 
 import (
 	"flag"
+	"learn-network-programming/activation"
 	hwproto "learn-network-programming/housework/v1"
 	robotmaid "learn-network-programming/server"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"google.golang.org/grpc"
 )
@@ -30,14 +34,33 @@ func main() {
 	// register service to server
 	hwproto.RegisterRobotMaidServer(server, rosie)
 
-	// create TCP listener
-	tcpListener, err := net.Listen("tcp", addr)
+	// use the listener passed by systemd if any, create TCP listener otherwise
+	tcpListener, err := activation.Listener()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// log listening
-	log.Printf("Listening for TLS connections on %s ...\n", addr)
+	if tcpListener == nil {
+		tcpListener, err = net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// stop gracefully on interrupt or termination
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		_, _ = activation.Notify(activation.Stopping)
+		server.GracefulStop()
+	}()
+
+	// log listening, tell the supervisor (if any) we're ready
+	log.Printf("Listening for TLS connections on %s ...\n", tcpListener.Addr())
+	if _, err := activation.Notify(activation.Ready); err != nil {
+		log.Printf("notify: %v", err)
+	}
 
 	// run server
 	err = server.Serve(tcpListener)
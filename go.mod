@@ -5,6 +5,7 @@ go 1.22.0
 toolchain go1.23.4
 
 require (
+	github.com/andybalholm/brotli v1.1.1
 	github.com/fsnotify/fsnotify v1.4.7
 	go.uber.org/multierr v1.11.0
 	go.uber.org/zap v1.27.0